@@ -2,16 +2,14 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"github.com/yantology/golang_template/internal/app"
 	"github.com/yantology/golang_template/internal/config"
-	"github.com/yantology/golang_template/internal/server"
 )
 
 func main() {
@@ -23,16 +21,15 @@ func main() {
 
 	log.Printf("Starting application in %s environment", cfg.Server.Env)
 
-	// For simple template, database is optional
-	var db *sql.DB
-
-	// Initialize and start server
-	srv := server.New(cfg, db)
+	// Build database, logger, repositories and services
+	application, err := app.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %v", err)
+	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Server starting on %s:%s", cfg.Server.Host, cfg.Server.Port)
-		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
+		if err := application.Start(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
@@ -45,12 +42,12 @@ func main() {
 	log.Println("Shutting down server...")
 
 	// Create a context with timeout for graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	// Shutdown server
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+	// Shutdown server and release resources
+	if err := application.Shutdown(ctx); err != nil {
+		log.Printf("Shutdown error: %v", err)
 	}
 
 	log.Println("Server stopped")
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	"github.com/yantology/golang_template/pkg/response"
+)
+
+// CurrentAPIKey returns the API key the request was authenticated with, so
+// machine clients can check their key works
+func (h *AuthHandler) CurrentAPIKey(c *gin.Context) {
+	key, ok := auth.GetAPIKeyFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Failed to get API key", "API key not found in context")
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API key retrieved successfully", key)
+}
+
+// ListAPIKeys returns every API key without its secret
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.authService.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		h.handleError(c, "Failed to list API keys", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API keys retrieved successfully", keys)
+}
+
+// CreateAPIKey issues an API key; the key itself is only shown in this response
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req auth.CreateAPIKeyRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	key, err := h.authService.CreateAPIKey(c.Request.Context(), &req)
+	if err != nil {
+		h.handleUserError(c, "Failed to create API key", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "API key created; store it now, it will not be shown again", key)
+}
+
+// RevokeAPIKey stops the API key in the path from authenticating
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid API key ID", err.Error())
+		return
+	}
+
+	if err := h.authService.RevokeAPIKey(c.Request.Context(), id); err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			response.Error(c, http.StatusNotFound, "Failed to revoke API key", err.Error())
+			return
+		}
+		h.handleError(c, "Failed to revoke API key", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API key revoked successfully", nil)
+}
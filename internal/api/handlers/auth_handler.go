@@ -0,0 +1,520 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+	"github.com/yantology/golang_template/pkg/response"
+)
+
+// AuthHandler exposes auth.Service over HTTP
+type AuthHandler struct {
+	authService *auth.Service
+	validate    *validator.Validate
+}
+
+// RefreshTokenRequest is the payload for exchanging a refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// SessionResponse is a session as shown to its owner
+type SessionResponse struct {
+	*auth.Session
+	Current bool `json:"current"`
+}
+
+func NewAuthHandler(authService *auth.Service) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		validate:    validator.New(),
+	}
+}
+
+// Register creates a new user and starts a session for it
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req auth.RegisterRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+
+	result, err := h.authService.Register(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, "Registration failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "User registered successfully", result)
+}
+
+// Login authenticates a user with email and password
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req auth.LoginRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+
+	result, err := h.authService.Login(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, "Login failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Login successful", result)
+}
+
+// VerifyEmail redeems an email verification token
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req auth.VerifyEmailRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	user, err := h.authService.VerifyEmail(c.Request.Context(), req.Token)
+	if err != nil {
+		h.handleError(c, "Email verification failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Email verified successfully", user)
+}
+
+// ResendVerification sends a new verification token. It responds the same
+// way whether or not the email is registered.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req auth.ResendVerificationRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.authService.ResendVerification(c.Request.Context(), req.Email); err != nil {
+		h.handleError(c, "Resending verification failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, "If the email is registered and unverified, a verification link has been sent", nil)
+}
+
+// ForgotPassword sends a password reset token. It responds the same way
+// whether or not the email is registered.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req auth.ForgotPasswordRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		h.handleError(c, "Password reset request failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, "If the email is registered, a password reset link has been sent", nil)
+}
+
+// RequestMagicLink emails a passwordless login link bound to the caller's user agent
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var req auth.MagicLinkRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+
+	if err := h.authService.RequestMagicLink(c.Request.Context(), &req); err != nil {
+		h.handleError(c, "Login link request failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, "If the email is registered, a login link has been sent", nil)
+}
+
+// LoginWithMagicLink redeems a login link from the same user agent that requested it
+func (h *AuthHandler) LoginWithMagicLink(c *gin.Context) {
+	var req auth.MagicLinkLoginRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+
+	result, err := h.authService.LoginWithMagicLink(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, "Login failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Login successful", result)
+}
+
+// ResetPassword sets a new password using a reset token
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req auth.ResetPasswordRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		h.handleError(c, "Password reset failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Password reset successfully", nil)
+}
+
+// VerifyMFA completes a login that returned an MFA challenge
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req auth.VerifyMFARequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+
+	result, err := h.authService.VerifyMFA(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, "Two-factor verification failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Login successful", result)
+}
+
+// Refresh issues a new token pair for a valid refresh token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshTokenRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	tokens, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		h.handleError(c, "Token refresh failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Token refreshed successfully", tokens)
+}
+
+// Logout ends the session the request was authenticated with
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Logout failed", "token claims not found in context")
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), claims); err != nil {
+		h.handleError(c, "Logout failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Logged out successfully", nil)
+}
+
+// LogoutAll ends every session belonging to the authenticated user
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Logout failed", "token claims not found in context")
+		return
+	}
+
+	if err := h.authService.LogoutAllSessions(c.Request.Context(), claims); err != nil {
+		h.handleError(c, "Logout failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Logged out from all sessions successfully", nil)
+}
+
+// ChangePassword replaces the authenticated user's password
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Password change failed", "token claims not found in context")
+		return
+	}
+
+	var req auth.ChangePasswordRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.authService.ChangePassword(c.Request.Context(), claims, &req); err != nil {
+		h.handleError(c, "Password change failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Password changed successfully", nil)
+}
+
+// ChangeEmail sends a verification token to the new address; the email is
+// changed once that token is redeemed at /auth/verify-email
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Email change failed", "token claims not found in context")
+		return
+	}
+
+	var req auth.ChangeEmailRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.authService.ChangeEmail(c.Request.Context(), claims, &req); err != nil {
+		h.handleError(c, "Email change failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, "A verification link has been sent to the new email address", nil)
+}
+
+// ListSessions returns the authenticated user's sessions, marking the one
+// the request was made with
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	current, ok := auth.GetSessionFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Failed to list sessions", "session not found in context")
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), current.UserID)
+	if err != nil {
+		h.handleError(c, "Failed to list sessions", err)
+		return
+	}
+
+	result := make([]SessionResponse, len(sessions))
+	for i, session := range sessions {
+		result[i] = SessionResponse{
+			Session: session,
+			Current: session.ID == current.ID,
+		}
+	}
+
+	response.Success(c, http.StatusOK, "Sessions retrieved successfully", result)
+}
+
+// RevokeSession ends one of the authenticated user's sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	current, ok := auth.GetSessionFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Failed to revoke session", "session not found in context")
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid session ID", err.Error())
+		return
+	}
+
+	if err := h.authService.RevokeSession(c.Request.Context(), current.UserID, sessionID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			response.Error(c, http.StatusNotFound, "Failed to revoke session", err.Error())
+			return
+		}
+		h.handleError(c, "Failed to revoke session", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// EnrollMFA starts TOTP enrollment and returns the secret to add to an authenticator app
+func (h *AuthHandler) EnrollMFA(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Two-factor enrollment failed", "token claims not found in context")
+		return
+	}
+
+	var req auth.BeginMFAEnrollmentRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	setup, err := h.authService.BeginMFAEnrollment(c.Request.Context(), claims, req.CurrentPassword)
+	if err != nil {
+		h.handleError(c, "Two-factor enrollment failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Scan the URI with an authenticator app and confirm with a code", setup)
+}
+
+// ConfirmMFA enables MFA and returns the recovery codes
+func (h *AuthHandler) ConfirmMFA(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Two-factor confirmation failed", "token claims not found in context")
+		return
+	}
+
+	var req auth.ConfirmMFARequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	codes, err := h.authService.ConfirmMFAEnrollment(c.Request.Context(), claims, req.Code)
+	if err != nil {
+		h.handleError(c, "Two-factor confirmation failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Two-factor authentication enabled", gin.H{"recovery_codes": codes})
+}
+
+// DisableMFA turns off MFA for the authenticated user
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Disabling two-factor authentication failed", "token claims not found in context")
+		return
+	}
+
+	var req auth.DisableMFARequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.authService.DisableMFA(c.Request.Context(), claims, req.CurrentPassword, req.Code); err != nil {
+		h.handleError(c, "Disabling two-factor authentication failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes replaces the authenticated user's recovery codes
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Regenerating recovery codes failed", "token claims not found in context")
+		return
+	}
+
+	var req auth.RecoveryCodesRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(c.Request.Context(), claims, req.CurrentPassword)
+	if err != nil {
+		h.handleError(c, "Regenerating recovery codes failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Recovery codes regenerated", gin.H{"recovery_codes": codes})
+}
+
+// bind decodes the JSON body into req and validates it, writing a 400 response on failure
+func (h *AuthHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request format", err.Error())
+		return false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Validation failed", err.Error())
+		return false
+	}
+
+	return true
+}
+
+// handleError maps auth errors to HTTP responses
+func (h *AuthHandler) handleError(c *gin.Context, message string, err error) {
+	var lockout *auth.LockoutError
+	if errors.As(err, &lockout) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+	}
+
+	appErr := toAppError(err)
+	response.Error(c, appErr.GetStatusCode(), message, appErr.Message)
+}
+
+// toAppError translates auth package errors into application errors
+func toAppError(err error) *apperrors.AppError {
+	var oauthErr *auth.OAuthError
+	if errors.As(err, &oauthErr) {
+		return apperrors.NewBadRequestError(oauthErr.Error())
+	}
+
+	switch {
+	case errors.Is(err, auth.ErrUserAlreadyExists),
+		errors.Is(err, auth.ErrMFAAlreadyEnabled),
+		errors.Is(err, auth.ErrIdentityAlreadyLinked),
+		errors.Is(err, auth.ErrWebAuthnCredentialExists):
+		return apperrors.NewConflictError(err.Error())
+	case errors.Is(err, auth.ErrUnknownOIDCProvider):
+		return apperrors.NewNotFoundError(err.Error())
+	case errors.Is(err, auth.ErrOIDCDiscovery),
+		errors.Is(err, auth.ErrOIDCExchange):
+		return apperrors.New(apperrors.ErrorCodeExternalService, err.Error())
+	case errors.Is(err, auth.ErrTooManyAttempts):
+		return apperrors.New(apperrors.ErrorCodeTooManyRequests, err.Error())
+	case errors.Is(err, auth.ErrInvalidVerificationToken),
+		errors.Is(err, auth.ErrInvalidResetToken),
+		errors.Is(err, auth.ErrIncorrectPassword),
+		errors.Is(err, auth.ErrEmailUnchanged),
+		errors.Is(err, auth.ErrMFANotEnabled),
+		errors.Is(err, auth.ErrMFANotEnrolled),
+		errors.Is(err, auth.ErrScopeNotPermitted),
+		errors.Is(err, auth.ErrInvalidOIDCState),
+		errors.Is(err, auth.ErrInvalidOAuthClient),
+		errors.Is(err, auth.ErrInvalidWebAuthnChallenge),
+		errors.Is(err, auth.ErrInvalidWebAuthnResponse),
+		errors.Is(err, auth.ErrUnsupportedWebAuthnAttestation):
+		return apperrors.NewBadRequestError(err.Error())
+	case errors.Is(err, auth.ErrEmailNotVerified),
+		errors.Is(err, auth.ErrOIDCEmailNotVerified),
+		errors.Is(err, auth.ErrReauthenticationRequired):
+		return apperrors.NewForbiddenError(err.Error())
+	case errors.Is(err, auth.ErrRoleNotFound),
+		errors.Is(err, auth.ErrRoleNotAssigned),
+		errors.Is(err, auth.ErrOAuthClientNotFound),
+		errors.Is(err, auth.ErrOAuthConsentNotFound),
+		errors.Is(err, auth.ErrWebAuthnCredentialNotFound):
+		return apperrors.NewNotFoundError(err.Error())
+	case errors.Is(err, auth.ErrInvalidCredentials),
+		errors.Is(err, auth.ErrUserNotFound),
+		errors.Is(err, auth.ErrSessionNotFound),
+		errors.Is(err, auth.ErrInvalidSession),
+		errors.Is(err, auth.ErrRefreshTokenReused),
+		errors.Is(err, auth.ErrInvalidToken),
+		errors.Is(err, auth.ErrExpiredToken),
+		errors.Is(err, auth.ErrInvalidClaims),
+		errors.Is(err, auth.ErrTokenBlacklist),
+		errors.Is(err, auth.ErrInvalidMFACode),
+		errors.Is(err, auth.ErrInvalidAPIKey),
+		errors.Is(err, auth.ErrInvalidIDToken),
+		errors.Is(err, auth.ErrInvalidMagicLink),
+		errors.Is(err, auth.ErrUnknownOIDCSigner):
+		return apperrors.NewUnauthorizedError(err.Error())
+	}
+
+	if appErr, ok := apperrors.IsAppError(err); ok {
+		return appErr
+	}
+
+	return apperrors.Wrap(err, apperrors.ErrorCodeInternalServer, "Internal server error")
+}
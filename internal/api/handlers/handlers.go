@@ -1,13 +1,21 @@
 package handlers
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
 
-type Handler struct{}
+	"github.com/yantology/golang_template/internal/pkg/authz"
+)
 
-func NewHandler() *Handler {
-	return &Handler{}
+// Handler serves the application's own routes. Handlers for records with an
+// owner check access with authorizer.Authorize, passing the *gin.Context.
+type Handler struct {
+	authorizer *authz.Authorizer
+}
+
+func NewHandler(authorizer *authz.Authorizer) *Handler {
+	return &Handler{authorizer: authorizer}
 }
 
 func (h *Handler) HealthCheck(c *gin.Context) {
 	c.JSON(200, gin.H{"status": "ok"})
-}
\ No newline at end of file
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	"github.com/yantology/golang_template/pkg/response"
+)
+
+// The token, introspection, revocation and userinfo endpoints answer with
+// the bare RFC documents rather than response.Success, since OAuth client
+// libraries expect them unwrapped.
+
+// OAuthAuthorizePreview validates an authorization request for the signed in
+// user and tells the consent screen what the client is asking for
+func (h *AuthHandler) OAuthAuthorizePreview(c *gin.Context) {
+	userID, ok := auth.GetUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Authorization failed", "user not found in context")
+		return
+	}
+
+	var req auth.OAuthAuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request format", err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	req.UserID = userID
+	prompt, err := h.authService.PreviewOAuthAuthorization(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, "Authorization failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Authorization request is valid", prompt)
+}
+
+// OAuthAuthorize records the signed in user's consent decision and returns
+// the client redirect URI carrying the authorization code or the refusal
+func (h *AuthHandler) OAuthAuthorize(c *gin.Context) {
+	userID, ok := auth.GetUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Authorization failed", "user not found in context")
+		return
+	}
+
+	var req auth.OAuthAuthorizeRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	req.UserID = userID
+	redirect, err := h.authService.AuthorizeOAuthClient(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, "Authorization failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Redirect the user back to the client", redirect)
+}
+
+// OAuthToken is the RFC 6749 token endpoint
+func (h *AuthHandler) OAuthToken(c *gin.Context) {
+	var req auth.OAuthTokenRequest
+	if !h.bindOAuthForm(c, &req, &req.OAuthClientAuth) {
+		return
+	}
+
+	tokens, err := h.authService.ExchangeOAuthToken(c.Request.Context(), &req)
+	if err != nil {
+		h.handleOAuthError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, tokens)
+}
+
+// OAuthIntrospect is the RFC 7662 token introspection endpoint
+func (h *AuthHandler) OAuthIntrospect(c *gin.Context) {
+	var req auth.OAuthIntrospectionRequest
+	if !h.bindOAuthForm(c, &req, &req.OAuthClientAuth) {
+		return
+	}
+
+	introspection, err := h.authService.IntrospectOAuthToken(c.Request.Context(), &req)
+	if err != nil {
+		h.handleOAuthError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, introspection)
+}
+
+// OAuthRevoke is the RFC 7009 token revocation endpoint. It answers 200 for
+// unknown tokens too.
+func (h *AuthHandler) OAuthRevoke(c *gin.Context) {
+	var req auth.OAuthRevocationRequest
+	if !h.bindOAuthForm(c, &req, &req.OAuthClientAuth) {
+		return
+	}
+
+	if err := h.authService.RevokeOAuthToken(c.Request.Context(), &req); err != nil {
+		h.handleOAuthError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// OAuthUserInfo describes the user an OAuth access token acts for. The email
+// address is only included when the token has the email scope.
+func (h *AuthHandler) OAuthUserInfo(c *gin.Context) {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Token does not act for a user",
+		})
+		return
+	}
+
+	info := gin.H{"sub": user.ID}
+	if claims, ok := auth.GetClaimsFromContext(c); ok && claims.HasScope("email") {
+		info["email"] = user.Email
+		info["email_verified"] = user.IsEmailVerified()
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// ListOAuthConsents returns the clients the authenticated user has authorised
+func (h *AuthHandler) ListOAuthConsents(c *gin.Context) {
+	userID, ok := auth.GetUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Failed to list consents", "user not found in context")
+		return
+	}
+
+	consents, err := h.authService.ListOAuthConsents(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "Failed to list consents", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Consents retrieved successfully", consents)
+}
+
+// RevokeOAuthConsent withdraws the authenticated user's consent for the
+// client in the path, revoking the tokens it holds for them
+func (h *AuthHandler) RevokeOAuthConsent(c *gin.Context) {
+	userID, ok := auth.GetUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Failed to revoke consent", "user not found in context")
+		return
+	}
+
+	clientID, err := uuid.Parse(c.Param("client_id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid client ID", err.Error())
+		return
+	}
+
+	if err := h.authService.RevokeOAuthConsent(c.Request.Context(), userID, clientID); err != nil {
+		h.handleError(c, "Failed to revoke consent", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Consent revoked successfully", nil)
+}
+
+// ListOAuthClients returns every registered OAuth client without its secret
+func (h *AuthHandler) ListOAuthClients(c *gin.Context) {
+	clients, err := h.authService.ListOAuthClients(c.Request.Context())
+	if err != nil {
+		h.handleError(c, "Failed to list OAuth clients", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "OAuth clients retrieved successfully", clients)
+}
+
+// RegisterOAuthClient registers an OAuth client; a confidential client's
+// secret is only shown in this response
+func (h *AuthHandler) RegisterOAuthClient(c *gin.Context) {
+	var req auth.RegisterOAuthClientRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	client, err := h.authService.RegisterOAuthClient(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, "Failed to register OAuth client", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "OAuth client registered; store the secret now, it will not be shown again", client)
+}
+
+// DeleteOAuthClient removes the OAuth client in the path along with its grants
+func (h *AuthHandler) DeleteOAuthClient(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid client ID", err.Error())
+		return
+	}
+
+	if err := h.authService.DeleteOAuthClient(c.Request.Context(), id); err != nil {
+		h.handleError(c, "Failed to delete OAuth client", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "OAuth client deleted successfully", nil)
+}
+
+// bindOAuthForm decodes a form encoded OAuth request into req, taking client
+// credentials from HTTP Basic authentication when present
+func (h *AuthHandler) bindOAuthForm(c *gin.Context, req interface{}, client *auth.OAuthClientAuth) bool {
+	if err := c.ShouldBind(req); err != nil {
+		h.handleOAuthError(c, &auth.OAuthError{Code: auth.OAuthErrInvalidRequest, Description: "malformed request body"})
+		return false
+	}
+
+	if username, password, ok := c.Request.BasicAuth(); ok {
+		// RFC 6749 section 2.3.1: credentials are form encoded before Basic encoding
+		id, idErr := url.QueryUnescape(username)
+		secret, secretErr := url.QueryUnescape(password)
+		if idErr != nil || secretErr != nil {
+			h.handleOAuthError(c, &auth.OAuthError{Code: auth.OAuthErrInvalidClient, Description: "malformed client credentials"})
+			return false
+		}
+		client.ClientID = id
+		client.ClientSecret = secret
+	}
+
+	return true
+}
+
+// handleOAuthError writes an RFC 6749 error response
+func (h *AuthHandler) handleOAuthError(c *gin.Context, err error) {
+	var oauthErr *auth.OAuthError
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &auth.OAuthError{Code: auth.OAuthErrServerError}
+	}
+
+	status := http.StatusBadRequest
+	switch oauthErr.Code {
+	case auth.OAuthErrInvalidClient:
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		status = http.StatusUnauthorized
+	case auth.OAuthErrServerError:
+		status = http.StatusInternalServerError
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, oauthErr)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	"github.com/yantology/golang_template/pkg/response"
+)
+
+// oidcBindingCookie holds the secret tying a social login to the browser
+// that started it
+const oidcBindingCookie = "oidc_binding"
+
+// OIDCLogin starts a social login and returns the provider URL the client
+// should send the user to. The browser binding is set as a cookie scoped to
+// the callback, which must be called from the same browser.
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	authorization, err := h.authService.BeginOIDCLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		h.handleError(c, "Social login failed", err)
+		return
+	}
+
+	callbackPath := path.Join(c.Request.URL.Path, "callback")
+	setOIDCBindingCookie(c, callbackPath, authorization.Binding, int(authorization.ExpiresIn.Seconds()), authorization.SecureBinding)
+
+	response.Success(c, http.StatusOK, "Redirect the user to the authorization URL", authorization)
+}
+
+// OIDCCallback completes a social login with the code and state the provider
+// appended to the redirect URL
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	var req auth.OIDCCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request format", err.Error())
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	req.Provider = c.Param("provider")
+	req.Binding, _ = c.Cookie(oidcBindingCookie)
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+
+	// The binding is single use whatever the outcome
+	setOIDCBindingCookie(c, c.Request.URL.Path, "", -1, false)
+
+	result, err := h.authService.CompleteOIDCLogin(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, "Social login failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Login successful", result)
+}
+
+// ListIdentities returns the external identities linked to the authenticated user
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	userID, ok := auth.GetUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Failed to list identities", "user not found in context")
+		return
+	}
+
+	identities, err := h.authService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "Failed to list identities", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Identities retrieved successfully", identities)
+}
+
+// setOIDCBindingCookie sets the binding cookie for path, deleting it when
+// maxAge is negative. SameSite=Lax lets the provider's top-level redirect
+// back to the callback carry it. secure comes from the configured redirect
+// URL rather than the request, whose scheme headers any client can forge.
+func setOIDCBindingCookie(c *gin.Context, path, binding string, maxAge int, secure bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcBindingCookie,
+		Value:    binding,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	"github.com/yantology/golang_template/pkg/response"
+)
+
+// ListRoles returns every role with its permissions
+func (h *AuthHandler) ListRoles(c *gin.Context) {
+	roles, err := h.authService.ListRoles(c.Request.Context())
+	if err != nil {
+		h.handleError(c, "Failed to list roles", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Roles retrieved successfully", roles)
+}
+
+// ListUserRoles returns the roles assigned to the user in the path
+func (h *AuthHandler) ListUserRoles(c *gin.Context) {
+	userID, ok := h.userIDParam(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.authService.ListUserRoles(c.Request.Context(), userID)
+	if err != nil {
+		h.handleUserError(c, "Failed to list user roles", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "User roles retrieved successfully", roles)
+}
+
+// AssignRole gives the user in the path a role
+func (h *AuthHandler) AssignRole(c *gin.Context) {
+	userID, ok := h.userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req auth.AssignRoleRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.authService.AssignRole(c.Request.Context(), userID, req.Role); err != nil {
+		h.handleUserError(c, "Failed to assign role", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Role assigned successfully", nil)
+}
+
+// RevokeRole takes the role in the path away from the user in the path
+func (h *AuthHandler) RevokeRole(c *gin.Context) {
+	userID, ok := h.userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.RevokeRole(c.Request.Context(), userID, c.Param("role")); err != nil {
+		h.handleUserError(c, "Failed to revoke role", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Role revoked successfully", nil)
+}
+
+// DeactivateUser disables the user in the path and ends all of their sessions
+func (h *AuthHandler) DeactivateUser(c *gin.Context) {
+	userID, ok := h.userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.DeactivateUser(c.Request.Context(), userID); err != nil {
+		h.handleUserError(c, "Failed to deactivate user", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "User deactivated successfully", nil)
+}
+
+// handleUserError reports an unknown user in the path as 404 rather than the
+// 401 it means when authenticating
+func (h *AuthHandler) handleUserError(c *gin.Context, message string, err error) {
+	if errors.Is(err, auth.ErrUserNotFound) {
+		response.Error(c, http.StatusNotFound, message, err.Error())
+		return
+	}
+	h.handleError(c, message, err)
+}
+
+// userIDParam parses the :id path parameter, writing a 400 response on failure
+func (h *AuthHandler) userIDParam(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid user ID", err.Error())
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	"github.com/yantology/golang_template/pkg/response"
+)
+
+// BeginPasskeyRegistration returns the options to pass to
+// navigator.credentials.create for adding a passkey to the authenticated user
+func (h *AuthHandler) BeginPasskeyRegistration(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Passkey registration failed", "token claims not found in context")
+		return
+	}
+
+	options, err := h.authService.BeginWebAuthnRegistration(c.Request.Context(), claims)
+	if err != nil {
+		h.handleError(c, "Passkey registration failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Create a credential with these options", options)
+}
+
+// FinishPasskeyRegistration stores the credential the authenticator created
+func (h *AuthHandler) FinishPasskeyRegistration(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Passkey registration failed", "token claims not found in context")
+		return
+	}
+
+	var req auth.FinishWebAuthnRegistrationRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	credential, err := h.authService.FinishWebAuthnRegistration(c.Request.Context(), claims, &req)
+	if err != nil {
+		h.handleError(c, "Passkey registration failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Passkey registered successfully", credential)
+}
+
+// BeginPasskeyLogin returns the options to pass to navigator.credentials.get
+func (h *AuthHandler) BeginPasskeyLogin(c *gin.Context) {
+	options, err := h.authService.BeginWebAuthnLogin(c.Request.Context())
+	if err != nil {
+		h.handleError(c, "Login failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Sign in with a passkey using these options", options)
+}
+
+// FinishPasskeyLogin logs in with a passkey assertion
+func (h *AuthHandler) FinishPasskeyLogin(c *gin.Context) {
+	var req auth.FinishWebAuthnLoginRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+
+	result, err := h.authService.FinishWebAuthnLogin(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, "Login failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Login successful", result)
+}
+
+// ListPasskeys returns the authenticated user's passkeys
+func (h *AuthHandler) ListPasskeys(c *gin.Context) {
+	userID, ok := auth.GetUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Failed to list passkeys", "user not found in context")
+		return
+	}
+
+	credentials, err := h.authService.ListWebAuthnCredentials(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, "Failed to list passkeys", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Passkeys retrieved successfully", credentials)
+}
+
+// DeletePasskey removes one of the authenticated user's passkeys
+func (h *AuthHandler) DeletePasskey(c *gin.Context) {
+	claims, ok := auth.GetClaimsFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Failed to delete passkey", "token claims not found in context")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid passkey ID", err.Error())
+		return
+	}
+
+	if err := h.authService.DeleteWebAuthnCredential(c.Request.Context(), claims, id); err != nil {
+		h.handleError(c, "Failed to delete passkey", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Passkey deleted successfully", nil)
+}
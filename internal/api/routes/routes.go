@@ -2,9 +2,90 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+
 	"github.com/yantology/golang_template/internal/api/handlers"
+	"github.com/yantology/golang_template/internal/pkg/auth"
 )
 
 func SetupRoutes(r *gin.RouterGroup, h *handlers.Handler) {
 	r.GET("/ping", h.HealthCheck)
-}
\ No newline at end of file
+}
+
+// SetupAuthRoutes registers the /auth endpoints
+func SetupAuthRoutes(r *gin.RouterGroup, h *handlers.AuthHandler, m *auth.Middleware) {
+	authGroup := r.Group("/auth")
+	authGroup.POST("/register", h.Register)
+	authGroup.POST("/login", h.Login)
+	authGroup.POST("/refresh", h.Refresh)
+	authGroup.POST("/mfa/verify", h.VerifyMFA)
+	authGroup.POST("/verify-email", h.VerifyEmail)
+	authGroup.POST("/resend-verification", h.ResendVerification)
+	authGroup.POST("/forgot-password", h.ForgotPassword)
+	authGroup.POST("/reset-password", h.ResetPassword)
+	authGroup.POST("/magic-link", h.RequestMagicLink)
+	authGroup.POST("/magic-link/verify", h.LoginWithMagicLink)
+	authGroup.GET("/oidc/:provider", h.OIDCLogin)
+	authGroup.GET("/oidc/:provider/callback", h.OIDCCallback)
+	authGroup.POST("/passkeys/login/begin", h.BeginPasskeyLogin)
+	authGroup.POST("/passkeys/login/finish", h.FinishPasskeyLogin)
+
+	protected := authGroup.Group("", m.RequireAuth())
+	protected.POST("/logout", h.Logout)
+	protected.POST("/logout-all", h.LogoutAll)
+	protected.POST("/change-password", h.ChangePassword)
+	protected.POST("/change-email", h.ChangeEmail)
+	protected.GET("/sessions", h.ListSessions)
+	protected.DELETE("/sessions/:id", h.RevokeSession)
+	protected.POST("/mfa/enroll", h.EnrollMFA)
+	protected.POST("/mfa/confirm", h.ConfirmMFA)
+	protected.POST("/mfa/disable", h.DisableMFA)
+	protected.POST("/mfa/recovery-codes", h.RegenerateRecoveryCodes)
+	protected.GET("/identities", h.ListIdentities)
+	protected.POST("/passkeys/register/begin", h.BeginPasskeyRegistration)
+	protected.POST("/passkeys/register/finish", h.FinishPasskeyRegistration)
+	protected.GET("/passkeys", h.ListPasskeys)
+	protected.DELETE("/passkeys/:id", h.DeletePasskey)
+
+	authGroup.GET("/api-key", m.RequireAPIKey(), h.CurrentAPIKey)
+}
+
+// SetupOAuthRoutes registers the /oauth endpoints through which third-party
+// clients obtain and manage tokens
+func SetupOAuthRoutes(r *gin.RouterGroup, h *handlers.AuthHandler, m *auth.Middleware) {
+	oauthGroup := r.Group("/oauth")
+	oauthGroup.POST("/token", h.OAuthToken)
+	oauthGroup.POST("/introspect", h.OAuthIntrospect)
+	oauthGroup.POST("/revoke", h.OAuthRevoke)
+	oauthGroup.GET("/userinfo", m.RequireOAuthScope("profile"), h.OAuthUserInfo)
+
+	protected := oauthGroup.Group("", m.RequireAuth())
+	protected.GET("/authorize", h.OAuthAuthorizePreview)
+	protected.POST("/authorize", h.OAuthAuthorize)
+	protected.GET("/consents", h.ListOAuthConsents)
+	protected.DELETE("/consents/:client_id", h.RevokeOAuthConsent)
+}
+
+// SetupAdminRoutes registers the /admin endpoints. Every route requires the
+// admin role and the permission for the resource it manages.
+func SetupAdminRoutes(r *gin.RouterGroup, h *handlers.AuthHandler, m *auth.Middleware) {
+	adminGroup := r.Group("/admin", m.RequireAuth(), m.RequireRole(auth.RoleAdmin))
+
+	roles := adminGroup.Group("", m.RequirePermission(auth.PermissionManageRoles))
+	roles.GET("/roles", h.ListRoles)
+	roles.GET("/users/:id/roles", h.ListUserRoles)
+	roles.POST("/users/:id/roles", h.AssignRole)
+	roles.DELETE("/users/:id/roles/:role", h.RevokeRole)
+
+	users := adminGroup.Group("", m.RequirePermission(auth.PermissionManageUsers))
+	users.POST("/users/:id/deactivate", h.DeactivateUser)
+
+	apiKeys := adminGroup.Group("", m.RequirePermission(auth.PermissionManageAPIKeys))
+	apiKeys.GET("/api-keys", h.ListAPIKeys)
+	apiKeys.POST("/api-keys", h.CreateAPIKey)
+	apiKeys.DELETE("/api-keys/:id", h.RevokeAPIKey)
+
+	oauthClients := adminGroup.Group("", m.RequirePermission(auth.PermissionManageOAuthClients))
+	oauthClients.GET("/oauth/clients", h.ListOAuthClients)
+	oauthClients.POST("/oauth/clients", h.RegisterOAuthClient)
+	oauthClients.DELETE("/oauth/clients/:id", h.DeleteOAuthClient)
+}
@@ -0,0 +1,323 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/yantology/golang_template/internal/config"
+	"github.com/yantology/golang_template/internal/data/repositories"
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	"github.com/yantology/golang_template/internal/pkg/authz"
+	"github.com/yantology/golang_template/internal/pkg/database"
+	"github.com/yantology/golang_template/internal/pkg/logger"
+	"github.com/yantology/golang_template/internal/pkg/mailer"
+	"github.com/yantology/golang_template/internal/pkg/scheduler"
+	"github.com/yantology/golang_template/internal/server"
+)
+
+// App is the composition root: it builds every dependency from config.Config
+// and owns their lifecycle
+type App struct {
+	config      *config.Config
+	db          *sql.DB
+	logger      logger.Logger
+	jwtManager  *auth.JWTManager
+	authService *auth.Service
+	scheduler   *scheduler.Scheduler
+	server      *server.Server
+
+	// closers are run in reverse order during Shutdown
+	closers []closer
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// New builds the application from configuration. Anything already started is
+// torn down again if a later step fails.
+func New(cfg *config.Config) (*App, error) {
+	a := &App{
+		config: cfg,
+		logger: logger.NewLogrusLogger(cfg.Logger),
+	}
+
+	if err := a.init(); err != nil {
+		_ = a.Shutdown(context.Background())
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *App) init() error {
+	cfg := a.config
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		return err
+	}
+	a.db = db
+	a.addCloser("database", func(ctx context.Context) error {
+		return db.Close()
+	})
+
+	// Repositories
+	userRepo := repositories.NewUserRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	tokenBlacklist := repositories.NewTokenBlacklistRepository(db)
+	passwordResetRepo := repositories.NewPasswordResetRepository(db)
+	magicLinkRepo := repositories.NewMagicLinkRepository(db)
+	mfaRepo := repositories.NewMFARepository(db)
+	loginAttemptRepo := repositories.NewLoginAttemptRepository(db)
+	roleRepo := repositories.NewRoleRepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	identityRepo := repositories.NewIdentityRepository(db)
+	oidcStateRepo := repositories.NewOIDCStateRepository(db)
+	oauthClientRepo := repositories.NewOAuthClientRepository(db)
+	oauthCodeRepo := repositories.NewOAuthCodeRepository(db)
+	oauthGrantRepo := repositories.NewOAuthGrantRepository(db)
+	oauthConsentRepo := repositories.NewOAuthConsentRepository(db)
+	webauthnCredentialRepo := repositories.NewWebAuthnCredentialRepository(db)
+	webauthnChallengeRepo := repositories.NewWebAuthnChallengeRepository(db)
+
+	// Services
+	if err := a.initJWT(); err != nil {
+		return err
+	}
+	notifier, err := a.initMailer()
+	if err != nil {
+		return err
+	}
+	authOpts := []auth.ServiceOption{
+		auth.WithSecurityEventRecorder(auth.NewLoggerEventRecorder(a.logger)),
+		auth.WithTokenBlacklist(tokenBlacklist),
+		auth.WithPasswordHasher(auth.NewPasswordHasherWithParams(auth.Argon2Params{
+			Memory:      uint32(cfg.Auth.Argon2MemoryKiB),
+			Iterations:  uint32(cfg.Auth.Argon2Iterations),
+			Parallelism: uint8(cfg.Auth.Argon2Parallelism),
+			SaltLength:  uint32(cfg.Auth.Argon2SaltLength),
+			KeyLength:   uint32(cfg.Auth.Argon2KeyLength),
+		})),
+		auth.WithNotifier(notifier),
+		auth.WithEmailVerification(cfg.Auth.RequireEmailVerification, cfg.Auth.EmailVerificationTTL),
+		auth.WithPasswordReset(passwordResetRepo, auth.PasswordResetPolicy{
+			TTL:        cfg.Auth.PasswordResetTTL,
+			RateLimit:  cfg.Auth.PasswordResetRateLimit,
+			RateWindow: cfg.Auth.PasswordResetRateWindow,
+		}),
+		auth.WithMagicLinks(magicLinkRepo, auth.MagicLinkPolicy{
+			TTL:        cfg.Auth.MagicLinkTTL,
+			RateLimit:  cfg.Auth.MagicLinkRateLimit,
+			RateWindow: cfg.Auth.MagicLinkRateWindow,
+		}),
+		auth.WithMFA(mfaRepo, cfg.Auth.MFAIssuer, cfg.Auth.MFAChallengeTTL),
+		auth.WithRBAC(roleRepo, cfg.Auth.DefaultRole),
+		auth.WithAPIKeys(apiKeyRepo),
+		auth.WithOIDC(identityRepo, oidcStateRepo, cfg.OIDC.StateTTL, a.oidcProviders()...),
+		auth.WithOAuthServer(oauthClientRepo, oauthCodeRepo, oauthGrantRepo, oauthConsentRepo, auth.OAuthServerConfig{
+			AccessTokenTTL:  cfg.OAuth.AccessTokenTTL,
+			RefreshTokenTTL: cfg.OAuth.RefreshTokenTTL,
+			CodeTTL:         cfg.OAuth.CodeTTL,
+			Scopes:          cfg.OAuth.Scopes,
+		}),
+		auth.WithWebAuthn(webauthnCredentialRepo, webauthnChallengeRepo, auth.WebAuthnConfig{
+			RPID:         cfg.WebAuthn.RPID,
+			RPName:       cfg.WebAuthn.RPName,
+			Origins:      cfg.WebAuthn.Origins,
+			ChallengeTTL: cfg.WebAuthn.ChallengeTTL,
+		}),
+	}
+	if cfg.Auth.LockoutEnabled() {
+		authOpts = append(authOpts, auth.WithLockout(loginAttemptRepo, auth.LockoutPolicy{
+			AccountThreshold: cfg.Auth.LockoutAccountThreshold,
+			IPThreshold:      cfg.Auth.LockoutIPThreshold,
+			BaseDuration:     cfg.Auth.LockoutBaseDuration,
+			MaxDuration:      cfg.Auth.LockoutMaxDuration,
+			Window:           cfg.Auth.LockoutWindow,
+		}))
+	}
+	if cfg.Auth.ValidationCacheEnabled() {
+		authOpts = append(authOpts, auth.WithValidationCache(
+			auth.NewMemoryValidationCache(cfg.Auth.ValidationCacheSize, cfg.Auth.ValidationCacheTTL),
+		))
+	}
+	a.authService = auth.NewService(userRepo, sessionRepo, a.jwtManager, authOpts...)
+	a.addCloser("auth background deliveries", a.authService.WaitForBackground)
+
+	authorizer, err := a.initAuthz()
+	if err != nil {
+		return err
+	}
+
+	// Background jobs
+	if err := a.initScheduler(); err != nil {
+		return err
+	}
+
+	// HTTP server
+	a.server = server.New(cfg, server.Dependencies{
+		DB:          db,
+		Logger:      a.logger,
+		AuthService: a.authService,
+		JWTKeys:     a.jwtManager.KeySet(),
+		Authorizer:  authorizer,
+	})
+	a.addCloser("http server", a.server.Shutdown)
+
+	return nil
+}
+
+// initJWT loads the signing and verification keys and starts key rotation
+func (a *App) initJWT() error {
+	jwtCfg := a.config.JWT
+
+	var signingKey *auth.SigningKey
+	var err error
+	if jwtCfg.GeneratesKeys() {
+		signingKey, err = auth.GenerateSigningKey(jwtCfg.Algorithm)
+	} else {
+		signingKey, err = auth.LoadSigningKey(jwtCfg.Algorithm, jwtCfg.Secret, jwtCfg.PrivateKeyPath, jwtCfg.PublicKeyPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load JWT signing key: %w", err)
+	}
+
+	keySet := auth.NewKeySet(signingKey)
+	for _, path := range jwtCfg.VerificationKeyPaths {
+		key, err := auth.LoadSigningKey(jwtCfg.Algorithm, "", "", path)
+		if err != nil {
+			return fmt.Errorf("failed to load JWT verification key %s: %w", path, err)
+		}
+		keySet.Add(key)
+	}
+
+	a.jwtManager = auth.NewJWTManagerWithKeySet(keySet, jwtCfg.AccessTokenTTL, jwtCfg.RefreshTokenTTL, jwtCfg.Issuer, jwtCfg.Audience)
+
+	if jwtCfg.KeyRotationInterval > 0 {
+		source := auth.FileKeySource(jwtCfg.Algorithm, jwtCfg.PrivateKeyPath, jwtCfg.PublicKeyPath)
+		if jwtCfg.GeneratesKeys() {
+			source = auth.GeneratedKeySource(jwtCfg.Algorithm)
+		}
+
+		// Old keys must outlive the longest-lived token they signed
+		rotator := auth.NewKeyRotator(keySet, source, jwtCfg.KeyRotationInterval, jwtCfg.RefreshTokenTTL, a.logger)
+		rotator.Start()
+		a.addCloser("JWT key rotator", rotator.Stop)
+	}
+
+	return nil
+}
+
+// initAuthz loads the resource authorization rules, falling back to
+// authz.DefaultRules when no policy file is configured
+func (a *App) initAuthz() (*authz.Authorizer, error) {
+	var policy *authz.RulePolicy
+	var err error
+	if path := a.config.Authz.PolicyFile; path != "" {
+		policy, err = authz.LoadRuleFile(path)
+	} else {
+		policy, err = authz.NewRulePolicy(authz.DefaultRules())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load authorization policy: %w", err)
+	}
+
+	return authz.NewAuthorizer(policy), nil
+}
+
+// oidcProviders creates a client for every configured social login provider
+func (a *App) oidcProviders() []*auth.OIDCProvider {
+	providers := make([]*auth.OIDCProvider, 0, len(a.config.OIDC.Providers))
+	for _, p := range a.config.OIDC.Providers {
+		providers = append(providers, auth.NewOIDCProvider(auth.OIDCProviderConfig{
+			Name:         p.Name,
+			IssuerURL:    p.IssuerURL,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil))
+	}
+
+	return providers
+}
+
+// initMailer builds the mail transport and the notifier auth emails are sent through
+func (a *App) initMailer() (auth.Notifier, error) {
+	mailCfg := a.config.Mail
+
+	m, err := mailer.New(mailCfg, a.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mailer: %w", err)
+	}
+
+	renderer, err := mailer.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	return mailer.NewAuthNotifier(m, renderer, mailCfg.FromName, mailCfg.BaseURL), nil
+}
+
+// initScheduler registers maintenance jobs. An advisory lock keeps each job
+// on a single replica.
+func (a *App) initScheduler() error {
+	schedCfg := a.config.Scheduler
+	if !schedCfg.Enabled {
+		return nil
+	}
+
+	a.scheduler = scheduler.New(database.NewAdvisoryLocker(a.db), a.logger)
+
+	cleanupSchedule, err := scheduler.Parse(schedCfg.SessionCleanupSchedule)
+	if err != nil {
+		return fmt.Errorf("invalid session cleanup schedule: %w", err)
+	}
+
+	if err := a.scheduler.Register(scheduler.Job{
+		Name:     "session_cleanup",
+		Schedule: cleanupSchedule,
+		Jitter:   schedCfg.Jitter,
+		Run:      a.authService.CleanupExpiredSessions,
+	}); err != nil {
+		return err
+	}
+
+	a.addCloser("scheduler", a.scheduler.Stop)
+	return nil
+}
+
+// Start starts background jobs and the HTTP server, blocking until the server stops
+func (a *App) Start() error {
+	if a.scheduler != nil {
+		a.scheduler.Start()
+	}
+
+	a.logger.Infof("Server starting on %s", a.config.Server.GetAddress())
+	return a.server.Start()
+}
+
+// Shutdown tears dependencies down in the reverse order they were created
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		c := a.closers[i]
+		a.logger.Infof("Stopping %s", c.name)
+
+		if err := c.close(ctx); err != nil {
+			a.logger.WithError(err).Errorf("Failed to stop %s", c.name)
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (a *App) addCloser(name string, close func(ctx context.Context) error) {
+	a.closers = append(a.closers, closer{name: name, close: close})
+}
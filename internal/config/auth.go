@@ -0,0 +1,169 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type AuthConfig struct {
+	ValidationCacheSize      int           `json:"validation_cache_size"`
+	ValidationCacheTTL       time.Duration `json:"validation_cache_ttl"`
+	RequireEmailVerification bool          `json:"require_email_verification"`
+	EmailVerificationTTL     time.Duration `json:"email_verification_ttl"`
+	PasswordResetTTL         time.Duration `json:"password_reset_ttl"`
+	PasswordResetRateLimit   int           `json:"password_reset_rate_limit"`
+	PasswordResetRateWindow  time.Duration `json:"password_reset_rate_window"`
+	MFAIssuer                string        `json:"mfa_issuer"`
+	MFAChallengeTTL          time.Duration `json:"mfa_challenge_ttl"`
+	LockoutAccountThreshold  int           `json:"lockout_account_threshold"`
+	LockoutIPThreshold       int           `json:"lockout_ip_threshold"`
+	LockoutBaseDuration      time.Duration `json:"lockout_base_duration"`
+	LockoutMaxDuration       time.Duration `json:"lockout_max_duration"`
+	LockoutWindow            time.Duration `json:"lockout_window"`
+	DefaultRole              string        `json:"default_role"`
+	MagicLinkTTL             time.Duration `json:"magic_link_ttl"`
+	MagicLinkRateLimit       int           `json:"magic_link_rate_limit"`
+	MagicLinkRateWindow      time.Duration `json:"magic_link_rate_window"`
+	Argon2MemoryKiB          int           `json:"argon2_memory_kib"`
+	Argon2Iterations         int           `json:"argon2_iterations"`
+	Argon2Parallelism        int           `json:"argon2_parallelism"`
+	Argon2SaltLength         int           `json:"argon2_salt_length"`
+	Argon2KeyLength          int           `json:"argon2_key_length"`
+}
+
+// LoadAuthConfig loads authentication configuration from Viper
+func LoadAuthConfig() AuthConfig {
+	return AuthConfig{
+		ValidationCacheSize:      viper.GetInt("auth.validation_cache_size"),
+		ValidationCacheTTL:       viper.GetDuration("auth.validation_cache_ttl"),
+		RequireEmailVerification: viper.GetBool("auth.require_email_verification"),
+		EmailVerificationTTL:     viper.GetDuration("auth.email_verification_ttl"),
+		PasswordResetTTL:         viper.GetDuration("auth.password_reset_ttl"),
+		PasswordResetRateLimit:   viper.GetInt("auth.password_reset_rate_limit"),
+		PasswordResetRateWindow:  viper.GetDuration("auth.password_reset_rate_window"),
+		MFAIssuer:                viper.GetString("auth.mfa_issuer"),
+		MFAChallengeTTL:          viper.GetDuration("auth.mfa_challenge_ttl"),
+		LockoutAccountThreshold:  viper.GetInt("auth.lockout_account_threshold"),
+		LockoutIPThreshold:       viper.GetInt("auth.lockout_ip_threshold"),
+		LockoutBaseDuration:      viper.GetDuration("auth.lockout_base_duration"),
+		LockoutMaxDuration:       viper.GetDuration("auth.lockout_max_duration"),
+		LockoutWindow:            viper.GetDuration("auth.lockout_window"),
+		DefaultRole:              viper.GetString("auth.default_role"),
+		MagicLinkTTL:             viper.GetDuration("auth.magic_link_ttl"),
+		MagicLinkRateLimit:       viper.GetInt("auth.magic_link_rate_limit"),
+		MagicLinkRateWindow:      viper.GetDuration("auth.magic_link_rate_window"),
+		Argon2MemoryKiB:          viper.GetInt("auth.argon2_memory_kib"),
+		Argon2Iterations:         viper.GetInt("auth.argon2_iterations"),
+		Argon2Parallelism:        viper.GetInt("auth.argon2_parallelism"),
+		Argon2SaltLength:         viper.GetInt("auth.argon2_salt_length"),
+		Argon2KeyLength:          viper.GetInt("auth.argon2_key_length"),
+	}
+}
+
+// Validate validates authentication configuration
+func (c AuthConfig) Validate() error {
+	var errs []error
+
+	if c.ValidationCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("validation cache size cannot be negative"))
+	}
+
+	if c.ValidationCacheEnabled() && c.ValidationCacheTTL <= 0 {
+		errs = append(errs, fmt.Errorf("validation cache TTL must be positive when the cache is enabled"))
+	}
+
+	if c.EmailVerificationTTL <= 0 {
+		errs = append(errs, fmt.Errorf("email verification TTL must be positive"))
+	}
+
+	if c.PasswordResetTTL <= 0 {
+		errs = append(errs, fmt.Errorf("password reset TTL must be positive"))
+	}
+
+	if c.PasswordResetRateLimit < 0 {
+		errs = append(errs, fmt.Errorf("password reset rate limit cannot be negative"))
+	}
+
+	if c.PasswordResetRateLimit > 0 && c.PasswordResetRateWindow <= 0 {
+		errs = append(errs, fmt.Errorf("password reset rate window must be positive when the rate limit is enabled"))
+	}
+
+	if c.MFAIssuer == "" {
+		errs = append(errs, fmt.Errorf("MFA issuer is required"))
+	}
+
+	if c.MFAChallengeTTL <= 0 {
+		errs = append(errs, fmt.Errorf("MFA challenge TTL must be positive"))
+	}
+
+	if c.LockoutAccountThreshold < 0 || c.LockoutIPThreshold < 0 {
+		errs = append(errs, fmt.Errorf("lockout thresholds cannot be negative"))
+	}
+
+	if c.LockoutEnabled() {
+		if c.LockoutBaseDuration <= 0 {
+			errs = append(errs, fmt.Errorf("lockout base duration must be positive"))
+		}
+
+		if c.LockoutMaxDuration < c.LockoutBaseDuration {
+			errs = append(errs, fmt.Errorf("lockout max duration cannot be less than the base duration"))
+		}
+
+		if c.LockoutWindow <= 0 {
+			errs = append(errs, fmt.Errorf("lockout window must be positive"))
+		}
+	}
+
+	if c.MagicLinkTTL <= 0 {
+		errs = append(errs, fmt.Errorf("magic link TTL must be positive"))
+	}
+
+	if c.MagicLinkRateLimit < 0 {
+		errs = append(errs, fmt.Errorf("magic link rate limit cannot be negative"))
+	}
+
+	if c.MagicLinkRateLimit > 0 && c.MagicLinkRateWindow <= 0 {
+		errs = append(errs, fmt.Errorf("magic link rate window must be positive when the rate limit is enabled"))
+	}
+
+	if c.Argon2Iterations < 1 {
+		errs = append(errs, fmt.Errorf("argon2 iterations must be at least 1"))
+	}
+
+	if c.Argon2Parallelism < 1 || c.Argon2Parallelism > 255 {
+		errs = append(errs, fmt.Errorf("argon2 parallelism must be between 1 and 255"))
+	}
+
+	// Argon2 needs at least 8 KiB of memory per lane
+	if c.Argon2MemoryKiB < 8*c.Argon2Parallelism || c.Argon2MemoryKiB > 4*1024*1024 {
+		errs = append(errs, fmt.Errorf("argon2 memory must be at least 8 KiB per unit of parallelism and at most 4 GiB"))
+	}
+
+	// RFC 9106 recommends a 128-bit salt; 64 bits is the floor for any salt
+	if c.Argon2SaltLength < 8 {
+		errs = append(errs, fmt.Errorf("argon2 salt length must be at least 8 bytes"))
+	}
+
+	if c.Argon2SaltLength > 1024 || c.Argon2KeyLength > 1024 {
+		errs = append(errs, fmt.Errorf("argon2 salt and key lengths must be at most 1024 bytes"))
+	}
+
+	if c.Argon2KeyLength < 16 {
+		errs = append(errs, fmt.Errorf("argon2 key length must be at least 16 bytes"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidationCacheEnabled returns true if sessions and users should be cached during token validation
+func (c AuthConfig) ValidationCacheEnabled() bool {
+	return c.ValidationCacheSize > 0
+}
+
+// LockoutEnabled returns true if failed logins are tracked per account or per client address
+func (c AuthConfig) LockoutEnabled() bool {
+	return c.LockoutAccountThreshold > 0 || c.LockoutIPThreshold > 0
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+type AuthzConfig struct {
+	PolicyFile string `json:"policy_file"`
+}
+
+// LoadAuthzConfig loads resource authorization configuration from Viper
+func LoadAuthzConfig() AuthzConfig {
+	return AuthzConfig{
+		PolicyFile: viper.GetString("authz.policy_file"),
+	}
+}
+
+// Validate validates resource authorization configuration. The rules
+// themselves are checked when the policy is loaded.
+func (c AuthzConfig) Validate() error {
+	var errs []error
+
+	if c.PolicyFile != "" {
+		if _, err := os.Stat(c.PolicyFile); err != nil {
+			errs = append(errs, fmt.Errorf("authorization policy file %s is not readable: %w", c.PolicyFile, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
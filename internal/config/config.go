@@ -1,18 +1,67 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Logger   LoggerConfig   `json:"logger"`
-	JWT      JWTConfig      `json:"jwt"`
+	Server    ServerConfig    `json:"server"`
+	Database  DatabaseConfig  `json:"database"`
+	Logger    LoggerConfig    `json:"logger"`
+	JWT       JWTConfig       `json:"jwt"`
+	Auth      AuthConfig      `json:"auth"`
+	Authz     AuthzConfig     `json:"authz"`
+	OIDC      OIDCConfig      `json:"oidc"`
+	OAuth     OAuthConfig     `json:"oauth"`
+	WebAuthn  WebAuthnConfig  `json:"webauthn"`
+	Scheduler SchedulerConfig `json:"scheduler"`
+	Mail      MailConfig      `json:"mail"`
 }
 
+// Load reads configuration from defaults, config file and APP_* environment
+// variables, then validates every section
 func Load() (*Config, error) {
-	return &Config{
-		Server: ServerConfig{
-			Port: "8080",
-			Host: "localhost",
-			Env:  "development",
-		},
-	}, nil
-}
\ No newline at end of file
+	if err := InitViper(); err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Server:    LoadServerConfig(),
+		Database:  LoadDatabaseConfig(),
+		Logger:    LoadLoggerConfig(),
+		JWT:       LoadJWTConfig(),
+		Auth:      LoadAuthConfig(),
+		Authz:     LoadAuthzConfig(),
+		OIDC:      LoadOIDCConfig(),
+		OAuth:     LoadOAuthConfig(),
+		WebAuthn:  LoadWebAuthnConfig(),
+		Scheduler: LoadSchedulerConfig(),
+		Mail:      LoadMailConfig(),
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate validates all configuration sections and reports every problem at once
+func (c *Config) Validate() error {
+	isProduction := c.Server.IsProduction()
+
+	return errors.Join(
+		c.Server.Validate(),
+		c.Database.Validate(isProduction),
+		c.Logger.Validate(),
+		c.JWT.Validate(isProduction),
+		c.Auth.Validate(),
+		c.Authz.Validate(),
+		c.OIDC.Validate(),
+		c.OAuth.Validate(),
+		c.WebAuthn.Validate(),
+		c.Scheduler.Validate(),
+		c.Mail.Validate(isProduction),
+	)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -46,52 +47,54 @@ func LoadDatabaseConfig() DatabaseConfig {
 
 // Validate validates database configuration
 func (c DatabaseConfig) Validate(isProduction bool) error {
+	var errs []error
+
 	if c.Type != PostgreSQL {
-		return fmt.Errorf("invalid database type: %s (only postgres is supported)", c.Type)
+		errs = append(errs, fmt.Errorf("invalid database type: %s (only postgres is supported)", c.Type))
 	}
 
 	if c.Host == "" {
-		return fmt.Errorf("database host is required")
+		errs = append(errs, fmt.Errorf("database host is required"))
 	}
 
 	if c.Port == "" {
-		return fmt.Errorf("database port is required")
+		errs = append(errs, fmt.Errorf("database port is required"))
 	}
 
 	if c.User == "" {
-		return fmt.Errorf("database user is required")
+		errs = append(errs, fmt.Errorf("database user is required"))
 	}
 
 	if c.Name == "" {
-		return fmt.Errorf("database name is required")
+		errs = append(errs, fmt.Errorf("database name is required"))
 	}
 
 	// In production, password should be provided
 	if isProduction && c.Password == "" {
-		return fmt.Errorf("database password is required in production environment")
+		errs = append(errs, fmt.Errorf("database password is required in production environment"))
 	}
 
 	if c.MaxOpenConns <= 0 {
-		return fmt.Errorf("max open connections must be positive")
+		errs = append(errs, fmt.Errorf("max open connections must be positive"))
 	}
 
 	if c.MaxIdleConns <= 0 {
-		return fmt.Errorf("max idle connections must be positive")
+		errs = append(errs, fmt.Errorf("max idle connections must be positive"))
 	}
 
 	if c.MaxIdleConns > c.MaxOpenConns {
-		return fmt.Errorf("max idle connections cannot be greater than max open connections")
+		errs = append(errs, fmt.Errorf("max idle connections cannot be greater than max open connections"))
 	}
 
 	if c.MaxLifetime <= 0 {
-		return fmt.Errorf("connection max lifetime must be positive")
+		errs = append(errs, fmt.Errorf("connection max lifetime must be positive"))
 	}
 
 	if c.MigrationPath == "" {
-		return fmt.Errorf("migration path is required")
+		errs = append(errs, fmt.Errorf("migration path is required"))
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // GetDSN returns the database connection string
@@ -103,4 +106,4 @@ func (c DatabaseConfig) GetDSN() string {
 // GetDriverName returns the database driver name
 func (c DatabaseConfig) GetDriverName() string {
 	return "postgres"
-}
\ No newline at end of file
+}
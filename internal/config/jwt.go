@@ -1,69 +1,90 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type JWTConfig struct {
-	Secret           string        `json:"secret"`
-	AccessTokenTTL   time.Duration `json:"access_token_ttl"`
-	RefreshTokenTTL  time.Duration `json:"refresh_token_ttl"`
-	Issuer           string        `json:"issuer"`
-	Audience         string        `json:"audience"`
-	Algorithm        string        `json:"algorithm"`
+	Secret               string        `json:"secret"`
+	AccessTokenTTL       time.Duration `json:"access_token_ttl"`
+	RefreshTokenTTL      time.Duration `json:"refresh_token_ttl"`
+	Issuer               string        `json:"issuer"`
+	Audience             string        `json:"audience"`
+	Algorithm            string        `json:"algorithm"`
+	PrivateKeyPath       string        `json:"private_key_path"`
+	PublicKeyPath        string        `json:"public_key_path"`
+	VerificationKeyPaths []string      `json:"verification_key_paths"`
+	KeyRotationInterval  time.Duration `json:"key_rotation_interval"`
+	KeyRotationSource    string        `json:"key_rotation_source"`
 }
 
 // LoadJWTConfig loads JWT configuration from Viper
 func LoadJWTConfig() JWTConfig {
 	return JWTConfig{
-		Secret:          viper.GetString("jwt.secret"),
-		AccessTokenTTL:  viper.GetDuration("jwt.access_token_ttl"),
-		RefreshTokenTTL: viper.GetDuration("jwt.refresh_token_ttl"),
-		Issuer:          viper.GetString("jwt.issuer"),
-		Audience:        viper.GetString("jwt.audience"),
-		Algorithm:       viper.GetString("jwt.algorithm"),
+		Secret:               viper.GetString("jwt.secret"),
+		AccessTokenTTL:       viper.GetDuration("jwt.access_token_ttl"),
+		RefreshTokenTTL:      viper.GetDuration("jwt.refresh_token_ttl"),
+		Issuer:               viper.GetString("jwt.issuer"),
+		Audience:             viper.GetString("jwt.audience"),
+		Algorithm:            viper.GetString("jwt.algorithm"),
+		PrivateKeyPath:       viper.GetString("jwt.private_key_path"),
+		PublicKeyPath:        viper.GetString("jwt.public_key_path"),
+		VerificationKeyPaths: viper.GetStringSlice("jwt.verification_key_paths"),
+		KeyRotationInterval:  viper.GetDuration("jwt.key_rotation_interval"),
+		KeyRotationSource:    viper.GetString("jwt.key_rotation_source"),
 	}
 }
 
 // Validate validates JWT configuration
 func (c JWTConfig) Validate(isProduction bool) error {
-	if c.Secret == "" {
-		return fmt.Errorf("JWT secret is required")
-	}
+	var errs []error
 
-	// In production, ensure the secret is strong enough
-	if isProduction {
-		if len(c.Secret) < 32 {
-			return fmt.Errorf("JWT secret must be at least 32 characters in production")
+	if c.IsAsymmetric() {
+		// RSA and ECDSA need a private key to sign our own tokens unless keys are generated
+		if c.PrivateKeyPath == "" && !c.GeneratesKeys() {
+			errs = append(errs, fmt.Errorf("JWT private key path is required for algorithm %s", c.Algorithm))
+		}
+	} else {
+		if c.Secret == "" {
+			errs = append(errs, fmt.Errorf("JWT secret is required"))
 		}
 
-		// Check if using default secret
-		if c.Secret == "your-super-secret-key-change-this-in-production" {
-			return fmt.Errorf("please change the default JWT secret in production")
+		// In production, ensure the secret is strong enough
+		if isProduction {
+			if len(c.Secret) < 32 {
+				errs = append(errs, fmt.Errorf("JWT secret must be at least 32 characters in production"))
+			}
+
+			// Check if using default secret
+			if c.Secret == "your-super-secret-key-change-this-in-production" {
+				errs = append(errs, fmt.Errorf("please change the default JWT secret in production"))
+			}
 		}
 	}
 
 	if c.AccessTokenTTL <= 0 {
-		return fmt.Errorf("access token TTL must be positive")
+		errs = append(errs, fmt.Errorf("access token TTL must be positive"))
 	}
 
 	if c.RefreshTokenTTL <= 0 {
-		return fmt.Errorf("refresh token TTL must be positive")
+		errs = append(errs, fmt.Errorf("refresh token TTL must be positive"))
 	}
 
 	if c.AccessTokenTTL >= c.RefreshTokenTTL {
-		return fmt.Errorf("refresh token TTL must be greater than access token TTL")
+		errs = append(errs, fmt.Errorf("refresh token TTL must be greater than access token TTL"))
 	}
 
 	if c.Issuer == "" {
-		return fmt.Errorf("JWT issuer is required")
+		errs = append(errs, fmt.Errorf("JWT issuer is required"))
 	}
 
 	if c.Audience == "" {
-		return fmt.Errorf("JWT audience is required")
+		errs = append(errs, fmt.Errorf("JWT audience is required"))
 	}
 
 	validAlgorithms := map[string]bool{
@@ -79,8 +100,35 @@ func (c JWTConfig) Validate(isProduction bool) error {
 	}
 
 	if !validAlgorithms[c.Algorithm] {
-		return fmt.Errorf("invalid JWT algorithm: %s", c.Algorithm)
+		errs = append(errs, fmt.Errorf("invalid JWT algorithm: %s", c.Algorithm))
+	}
+
+	if c.KeyRotationInterval < 0 {
+		errs = append(errs, fmt.Errorf("JWT key rotation interval cannot be negative"))
+	}
+
+	if c.KeyRotationInterval > 0 && !c.IsAsymmetric() {
+		errs = append(errs, fmt.Errorf("JWT key rotation requires an RSA or ECDSA algorithm"))
+	}
+
+	validRotationSources := map[string]bool{
+		"file":     true,
+		"generate": true,
+	}
+
+	if !validRotationSources[c.KeyRotationSource] {
+		errs = append(errs, fmt.Errorf("invalid JWT key rotation source: %s (valid sources: file, generate)", c.KeyRotationSource))
 	}
 
-	return nil
-}
\ No newline at end of file
+	return errors.Join(errs...)
+}
+
+// IsAsymmetric returns true if the configured algorithm uses a key pair (RSA or ECDSA)
+func (c JWTConfig) IsAsymmetric() bool {
+	return strings.HasPrefix(c.Algorithm, "RS") || strings.HasPrefix(c.Algorithm, "ES")
+}
+
+// GeneratesKeys returns true if signing keys are generated in-process instead of read from files
+func (c JWTConfig) GeneratesKeys() bool {
+	return c.IsAsymmetric() && c.KeyRotationSource == "generate"
+}
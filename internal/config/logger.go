@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/viper"
@@ -27,6 +28,8 @@ func LoadLoggerConfig() LoggerConfig {
 
 // Validate validates logger configuration
 func (c LoggerConfig) Validate() error {
+	var errs []error
+
 	validLevels := map[string]bool{
 		"debug": true,
 		"info":  true,
@@ -37,7 +40,7 @@ func (c LoggerConfig) Validate() error {
 	}
 
 	if !validLevels[c.Level] {
-		return fmt.Errorf("invalid log level: %s (valid levels: debug, info, warn, error, fatal, panic)", c.Level)
+		errs = append(errs, fmt.Errorf("invalid log level: %s (valid levels: debug, info, warn, error, fatal, panic)", c.Level))
 	}
 
 	validFormats := map[string]bool{
@@ -46,7 +49,7 @@ func (c LoggerConfig) Validate() error {
 	}
 
 	if !validFormats[c.Format] {
-		return fmt.Errorf("invalid log format: %s (valid formats: json, text)", c.Format)
+		errs = append(errs, fmt.Errorf("invalid log format: %s (valid formats: json, text)", c.Format))
 	}
 
 	validOutputs := map[string]bool{
@@ -56,8 +59,8 @@ func (c LoggerConfig) Validate() error {
 	}
 
 	if !validOutputs[c.Output] {
-		return fmt.Errorf("invalid log output: %s (valid outputs: stdout, stderr, file)", c.Output)
+		errs = append(errs, fmt.Errorf("invalid log output: %s (valid outputs: stdout, stderr, file)", c.Output))
 	}
 
-	return nil
-}
\ No newline at end of file
+	return errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type MailConfig struct {
+	Transport      string        `json:"transport"`
+	From           string        `json:"from"`
+	FromName       string        `json:"from_name"`
+	BaseURL        string        `json:"base_url"`
+	SMTPHost       string        `json:"smtp_host"`
+	SMTPPort       int           `json:"smtp_port"`
+	SMTPUsername   string        `json:"smtp_username"`
+	SMTPPassword   string        `json:"-"`
+	SMTPEncryption string        `json:"smtp_encryption"`
+	Timeout        time.Duration `json:"timeout"`
+	FileDir        string        `json:"file_dir"`
+}
+
+// LoadMailConfig loads outbound email configuration from Viper
+func LoadMailConfig() MailConfig {
+	return MailConfig{
+		Transport:      viper.GetString("mail.transport"),
+		From:           viper.GetString("mail.from"),
+		FromName:       viper.GetString("mail.from_name"),
+		BaseURL:        viper.GetString("mail.base_url"),
+		SMTPHost:       viper.GetString("mail.smtp_host"),
+		SMTPPort:       viper.GetInt("mail.smtp_port"),
+		SMTPUsername:   viper.GetString("mail.smtp_username"),
+		SMTPPassword:   viper.GetString("mail.smtp_password"),
+		SMTPEncryption: viper.GetString("mail.smtp_encryption"),
+		Timeout:        viper.GetDuration("mail.timeout"),
+		FileDir:        viper.GetString("mail.file_dir"),
+	}
+}
+
+// Validate validates mail configuration. The log and file transports write
+// one-time tokens in plain text, so production requires smtp.
+func (c MailConfig) Validate(isProduction bool) error {
+	var errs []error
+
+	validTransports := map[string]bool{
+		"smtp": true,
+		"file": true,
+		"log":  true,
+	}
+
+	if !validTransports[c.Transport] {
+		errs = append(errs, fmt.Errorf("invalid mail transport: %s (valid transports: smtp, file, log)", c.Transport))
+	} else if isProduction && c.Transport != "smtp" {
+		errs = append(errs, fmt.Errorf("mail transport must be smtp in production, %s would expose one-time tokens", c.Transport))
+	}
+
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		errs = append(errs, fmt.Errorf("invalid mail from address %q: %w", c.From, err))
+	}
+
+	if u, err := url.Parse(c.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("mail base URL must be an absolute URL: %q", c.BaseURL))
+	}
+
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("mail timeout must be positive"))
+	}
+
+	switch c.Transport {
+	case "smtp":
+		if c.SMTPHost == "" {
+			errs = append(errs, fmt.Errorf("SMTP host is required for the smtp mail transport"))
+		}
+
+		if c.SMTPPort <= 0 || c.SMTPPort > 65535 {
+			errs = append(errs, fmt.Errorf("invalid SMTP port: %d", c.SMTPPort))
+		}
+
+		validEncryptions := map[string]bool{
+			"none":     true,
+			"starttls": true,
+			"tls":      true,
+		}
+
+		if !validEncryptions[c.SMTPEncryption] {
+			errs = append(errs, fmt.Errorf("invalid SMTP encryption: %s (valid modes: none, starttls, tls)", c.SMTPEncryption))
+		}
+	case "file":
+		if c.FileDir == "" {
+			errs = append(errs, fmt.Errorf("mail file directory is required for the file mail transport"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// OAuthConfig configures the OAuth authorization server offered to
+// third-party clients
+type OAuthConfig struct {
+	AccessTokenTTL  time.Duration `json:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `json:"refresh_token_ttl"`
+	CodeTTL         time.Duration `json:"code_ttl"`
+	Scopes          []string      `json:"scopes"`
+}
+
+// LoadOAuthConfig loads OAuth authorization server configuration from Viper
+func LoadOAuthConfig() OAuthConfig {
+	return OAuthConfig{
+		AccessTokenTTL:  viper.GetDuration("oauth.access_token_ttl"),
+		RefreshTokenTTL: viper.GetDuration("oauth.refresh_token_ttl"),
+		CodeTTL:         viper.GetDuration("oauth.code_ttl"),
+		Scopes:          viper.GetStringSlice("oauth.scopes"),
+	}
+}
+
+// Validate validates OAuth authorization server configuration
+func (c OAuthConfig) Validate() error {
+	var errs []error
+
+	if c.AccessTokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("OAuth access token TTL must be positive"))
+	}
+
+	if c.RefreshTokenTTL <= c.AccessTokenTTL {
+		errs = append(errs, fmt.Errorf("OAuth refresh token TTL must be longer than the access token TTL"))
+	}
+
+	// RFC 6749 recommends authorization codes live at most 10 minutes
+	if c.CodeTTL <= 0 || c.CodeTTL > 10*time.Minute {
+		errs = append(errs, fmt.Errorf("OAuth code TTL must be between 0 and 10m"))
+	}
+
+	if len(c.Scopes) == 0 {
+		errs = append(errs, fmt.Errorf("at least one OAuth scope is required"))
+	}
+
+	return errors.Join(errs...)
+}
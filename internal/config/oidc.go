@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type OIDCConfig struct {
+	StateTTL  time.Duration        `json:"state_ttl"`
+	Providers []OIDCProviderConfig `json:"providers"`
+}
+
+// OIDCProviderConfig is one OpenID Connect provider, read from
+// oidc.<name>.* (APP_OIDC_<NAME>_*)
+type OIDCProviderConfig struct {
+	Name         string   `json:"name"`
+	IssuerURL    string   `json:"issuer_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"-"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+}
+
+// LoadOIDCConfig loads social login configuration from Viper. oidc.providers
+// lists the provider names to load.
+func LoadOIDCConfig() OIDCConfig {
+	cfg := OIDCConfig{
+		StateTTL: viper.GetDuration("oidc.state_ttl"),
+	}
+
+	for _, name := range viper.GetStringSlice("oidc.providers") {
+		prefix := "oidc." + name + "."
+		cfg.Providers = append(cfg.Providers, OIDCProviderConfig{
+			Name:         name,
+			IssuerURL:    viper.GetString(prefix + "issuer_url"),
+			ClientID:     viper.GetString(prefix + "client_id"),
+			ClientSecret: viper.GetString(prefix + "client_secret"),
+			RedirectURL:  viper.GetString(prefix + "redirect_url"),
+			Scopes:       viper.GetStringSlice(prefix + "scopes"),
+		})
+	}
+
+	return cfg
+}
+
+// Validate validates social login configuration
+func (c OIDCConfig) Validate() error {
+	var errs []error
+
+	if c.StateTTL <= 0 {
+		errs = append(errs, fmt.Errorf("OIDC state TTL must be positive"))
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range c.Providers {
+		if seen[p.Name] {
+			errs = append(errs, fmt.Errorf("OIDC provider %s is listed twice", p.Name))
+		}
+		seen[p.Name] = true
+
+		if u, err := url.Parse(p.IssuerURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("OIDC provider %s: issuer URL must be an absolute URL", p.Name))
+		}
+
+		if p.ClientID == "" {
+			errs = append(errs, fmt.Errorf("OIDC provider %s: client ID is required", p.Name))
+		}
+
+		if u, err := url.Parse(p.RedirectURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("OIDC provider %s: redirect URL must be an absolute URL", p.Name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type SchedulerConfig struct {
+	Enabled                bool          `json:"enabled"`
+	Jitter                 time.Duration `json:"jitter"`
+	SessionCleanupSchedule string        `json:"session_cleanup_schedule"`
+}
+
+// LoadSchedulerConfig loads background job configuration from Viper
+func LoadSchedulerConfig() SchedulerConfig {
+	return SchedulerConfig{
+		Enabled:                viper.GetBool("scheduler.enabled"),
+		Jitter:                 viper.GetDuration("scheduler.jitter"),
+		SessionCleanupSchedule: viper.GetString("scheduler.session_cleanup_schedule"),
+	}
+}
+
+// Validate validates scheduler configuration. Schedule specs are parsed when
+// jobs are registered.
+func (c SchedulerConfig) Validate() error {
+	var errs []error
+
+	if c.Jitter < 0 {
+		errs = append(errs, fmt.Errorf("scheduler jitter cannot be negative"))
+	}
+
+	if c.Enabled && c.SessionCleanupSchedule == "" {
+		errs = append(errs, fmt.Errorf("session cleanup schedule is required when the scheduler is enabled"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/spf13/viper"
@@ -17,6 +19,10 @@ type ServerConfig struct {
 	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
 	EnableCORS      bool          `json:"enable_cors"`
 	CORSOrigins     []string      `json:"cors_origins"`
+	// TrustedProxies are the addresses or CIDR ranges whose X-Forwarded-For
+	// and X-Real-IP headers are believed. Empty trusts none, so the client
+	// IP is the address of the TCP peer.
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
 // LoadServerConfig loads server configuration from Viper
@@ -31,17 +37,20 @@ func LoadServerConfig() ServerConfig {
 		ShutdownTimeout: viper.GetDuration("server.shutdown_timeout"),
 		EnableCORS:      viper.GetBool("server.enable_cors"),
 		CORSOrigins:     viper.GetStringSlice("server.cors_origins"),
+		TrustedProxies:  viper.GetStringSlice("server.trusted_proxies"),
 	}
 }
 
 // Validate validates server configuration
 func (c ServerConfig) Validate() error {
+	var errs []error
+
 	if c.Port == "" {
-		return fmt.Errorf("server port is required")
+		errs = append(errs, fmt.Errorf("server port is required"))
 	}
 
 	if c.Host == "" {
-		return fmt.Errorf("server host is required")
+		errs = append(errs, fmt.Errorf("server host is required"))
 	}
 
 	validEnvs := map[string]bool{
@@ -52,27 +61,34 @@ func (c ServerConfig) Validate() error {
 	}
 
 	if !validEnvs[c.Env] {
-		return fmt.Errorf("invalid environment: %s (must be one of: development, staging, production, test)", c.Env)
+		errs = append(errs, fmt.Errorf("invalid environment: %s (must be one of: development, staging, production, test)", c.Env))
 	}
 
 	if c.ReadTimeout <= 0 {
-		return fmt.Errorf("server read timeout must be positive")
+		errs = append(errs, fmt.Errorf("server read timeout must be positive"))
 	}
 
 	if c.WriteTimeout <= 0 {
-		return fmt.Errorf("server write timeout must be positive")
+		errs = append(errs, fmt.Errorf("server write timeout must be positive"))
 	}
 
 	if c.IdleTimeout <= 0 {
-		return fmt.Errorf("server idle timeout must be positive")
+		errs = append(errs, fmt.Errorf("server idle timeout must be positive"))
 	}
 
 	if c.ShutdownTimeout <= 0 {
-		return fmt.Errorf("server shutdown timeout must be positive")
+		errs = append(errs, fmt.Errorf("server shutdown timeout must be positive"))
 	}
 
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("trusted proxy must be an IP address or CIDR range: %q", proxy))
+			}
+		}
+	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // IsProduction returns true if environment is production
@@ -98,4 +114,4 @@ func (c ServerConfig) IsTest() bool {
 // GetAddress returns the server address in host:port format
 func (c ServerConfig) GetAddress() string {
 	return fmt.Sprintf("%s:%s", c.Host, c.Port)
-}
\ No newline at end of file
+}
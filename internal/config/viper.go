@@ -52,6 +52,7 @@ func setDefaults() {
 	viper.SetDefault("server.shutdown_timeout", "30s")
 	viper.SetDefault("server.enable_cors", true)
 	viper.SetDefault("server.cors_origins", []string{"*"})
+	viper.SetDefault("server.trusted_proxies", []string{})
 
 	// Database defaults
 	viper.SetDefault("database.type", "postgres")
@@ -73,6 +74,73 @@ func setDefaults() {
 	viper.SetDefault("jwt.issuer", "golang-template")
 	viper.SetDefault("jwt.audience", "golang-template-users")
 	viper.SetDefault("jwt.algorithm", "HS256")
+	viper.SetDefault("jwt.private_key_path", "")
+	viper.SetDefault("jwt.public_key_path", "")
+	viper.SetDefault("jwt.verification_key_paths", []string{})
+	viper.SetDefault("jwt.key_rotation_interval", "0s")
+	viper.SetDefault("jwt.key_rotation_source", "file")
+
+	// Auth defaults
+	viper.SetDefault("auth.validation_cache_size", 0)
+	viper.SetDefault("auth.validation_cache_ttl", "30s")
+	viper.SetDefault("auth.require_email_verification", false)
+	viper.SetDefault("auth.email_verification_ttl", "24h")
+	viper.SetDefault("auth.password_reset_ttl", "1h")
+	viper.SetDefault("auth.password_reset_rate_limit", 3)
+	viper.SetDefault("auth.password_reset_rate_window", "1h")
+	viper.SetDefault("auth.mfa_issuer", "Golang Template")
+	viper.SetDefault("auth.mfa_challenge_ttl", "5m")
+	viper.SetDefault("auth.lockout_account_threshold", 5)
+	viper.SetDefault("auth.lockout_ip_threshold", 50)
+	viper.SetDefault("auth.lockout_base_duration", "1m")
+	viper.SetDefault("auth.lockout_max_duration", "1h")
+	viper.SetDefault("auth.lockout_window", "1h")
+	viper.SetDefault("auth.default_role", "user")
+	viper.SetDefault("auth.magic_link_ttl", "15m")
+	viper.SetDefault("auth.magic_link_rate_limit", 3)
+	viper.SetDefault("auth.magic_link_rate_window", "1h")
+	viper.SetDefault("auth.argon2_memory_kib", 64*1024)
+	viper.SetDefault("auth.argon2_iterations", 3)
+	viper.SetDefault("auth.argon2_parallelism", 2)
+	viper.SetDefault("auth.argon2_salt_length", 16)
+	viper.SetDefault("auth.argon2_key_length", 32)
+
+	// Authz defaults
+	viper.SetDefault("authz.policy_file", "")
+
+	// OIDC defaults
+	viper.SetDefault("oidc.state_ttl", "10m")
+	viper.SetDefault("oidc.providers", []string{})
+
+	// OAuth authorization server defaults
+	viper.SetDefault("oauth.access_token_ttl", "1h")
+	viper.SetDefault("oauth.refresh_token_ttl", "720h")
+	viper.SetDefault("oauth.code_ttl", "1m")
+	viper.SetDefault("oauth.scopes", []string{"profile", "email"})
+
+	// WebAuthn defaults
+	viper.SetDefault("webauthn.rp_id", "localhost")
+	viper.SetDefault("webauthn.rp_name", "Golang Template")
+	viper.SetDefault("webauthn.origins", []string{"http://localhost:8080"})
+	viper.SetDefault("webauthn.challenge_ttl", "5m")
+
+	// Scheduler defaults
+	viper.SetDefault("scheduler.enabled", true)
+	viper.SetDefault("scheduler.jitter", "30s")
+	viper.SetDefault("scheduler.session_cleanup_schedule", "@every 1h")
+
+	// Mail defaults
+	viper.SetDefault("mail.transport", "log")
+	viper.SetDefault("mail.from", "no-reply@localhost")
+	viper.SetDefault("mail.from_name", "Golang Template")
+	viper.SetDefault("mail.base_url", "http://localhost:8080")
+	viper.SetDefault("mail.smtp_host", "")
+	viper.SetDefault("mail.smtp_port", 587)
+	viper.SetDefault("mail.smtp_username", "")
+	viper.SetDefault("mail.smtp_password", "")
+	viper.SetDefault("mail.smtp_encryption", "starttls")
+	viper.SetDefault("mail.timeout", "10s")
+	viper.SetDefault("mail.file_dir", "./tmp/mail")
 
 	// Logger defaults
 	viper.SetDefault("logger.level", "info")
@@ -81,4 +149,4 @@ func setDefaults() {
 	viper.SetDefault("logger.enable_caller", true)
 	viper.SetDefault("logger.enable_stacktrace", false)
 
-}
\ No newline at end of file
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// WebAuthnConfig configures passkey registration and login
+type WebAuthnConfig struct {
+	RPID         string        `json:"rp_id"`
+	RPName       string        `json:"rp_name"`
+	Origins      []string      `json:"origins"`
+	ChallengeTTL time.Duration `json:"challenge_ttl"`
+}
+
+// LoadWebAuthnConfig loads passkey configuration from Viper
+func LoadWebAuthnConfig() WebAuthnConfig {
+	return WebAuthnConfig{
+		RPID:         viper.GetString("webauthn.rp_id"),
+		RPName:       viper.GetString("webauthn.rp_name"),
+		Origins:      viper.GetStringSlice("webauthn.origins"),
+		ChallengeTTL: viper.GetDuration("webauthn.challenge_ttl"),
+	}
+}
+
+// Validate validates passkey configuration
+func (c WebAuthnConfig) Validate() error {
+	var errs []error
+
+	if c.RPID == "" || strings.Contains(c.RPID, "/") || strings.Contains(c.RPID, ":") {
+		errs = append(errs, fmt.Errorf("WebAuthn RP ID must be a bare domain such as example.com"))
+	}
+
+	if c.RPName == "" {
+		errs = append(errs, fmt.Errorf("WebAuthn RP name is required"))
+	}
+
+	if len(c.Origins) == 0 {
+		errs = append(errs, fmt.Errorf("at least one WebAuthn origin is required"))
+	}
+
+	// Browsers only allow an RP ID that is the origin's host or a parent domain of it
+	for _, origin := range c.Origins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			errs = append(errs, fmt.Errorf("WebAuthn origin must be a scheme and host without a path: %q", origin))
+			continue
+		}
+		if host := u.Hostname(); host != c.RPID && !strings.HasSuffix(host, "."+c.RPID) {
+			errs = append(errs, fmt.Errorf("WebAuthn origin %q is not within RP ID %q", origin, c.RPID))
+		}
+	}
+
+	if c.ChallengeTTL <= 0 {
+		errs = append(errs, fmt.Errorf("WebAuthn challenge TTL must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,4 @@
+ALTER TABLE users ALTER COLUMN is_active DROP NOT NULL;
+
+ALTER TABLE users ALTER COLUMN last_name DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN first_name DROP DEFAULT;
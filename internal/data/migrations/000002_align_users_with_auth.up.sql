@@ -0,0 +1,7 @@
+-- auth.User does not track names, so allow users to be created without them
+ALTER TABLE users ALTER COLUMN first_name SET DEFAULT '';
+ALTER TABLE users ALTER COLUMN last_name SET DEFAULT '';
+
+-- is_active is scanned into a non-nullable bool
+UPDATE users SET is_active = true WHERE is_active IS NULL;
+ALTER TABLE users ALTER COLUMN is_active SET NOT NULL;
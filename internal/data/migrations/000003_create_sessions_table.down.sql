@@ -0,0 +1,9 @@
+-- Drop trigger first
+DROP TRIGGER IF EXISTS update_sessions_updated_at ON sessions;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_sessions_expires_at;
+DROP INDEX IF EXISTS idx_sessions_user_id;
+
+-- Drop sessions table
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,23 @@
+-- Create sessions table
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token TEXT NOT NULL UNIQUE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create index for per-user lookups and logout-all
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+-- Create index for expired session cleanup
+CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+
+-- Reuse the updated_at trigger function from the users migration
+CREATE TRIGGER update_sessions_updated_at 
+    BEFORE UPDATE ON sessions 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
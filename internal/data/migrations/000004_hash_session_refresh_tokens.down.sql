@@ -0,0 +1,6 @@
+-- Raw tokens cannot be recovered from their hashes, so existing sessions are dropped
+DELETE FROM sessions;
+
+ALTER TABLE sessions ALTER COLUMN refresh_token_hash TYPE TEXT;
+
+ALTER TABLE sessions RENAME COLUMN refresh_token_hash TO refresh_token;
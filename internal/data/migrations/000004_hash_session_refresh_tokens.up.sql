@@ -0,0 +1,6 @@
+-- Store only a SHA-256 hash of the current refresh token
+ALTER TABLE sessions RENAME COLUMN refresh_token TO refresh_token_hash;
+
+UPDATE sessions SET refresh_token_hash = encode(sha256(refresh_token_hash::bytea), 'hex');
+
+ALTER TABLE sessions ALTER COLUMN refresh_token_hash TYPE CHAR(64);
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_revoked_tokens_expires_at;
+
+-- Drop revoked_tokens table
+DROP TABLE IF EXISTS revoked_tokens;
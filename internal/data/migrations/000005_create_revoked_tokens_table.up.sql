@@ -0,0 +1,9 @@
+-- Create revoked_tokens table holding the jti of access tokens revoked before expiry
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create index for expired entry cleanup
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
@@ -0,0 +1,3 @@
+-- Accounts created before email verification existed are treated as verified
+-- so that enabling auth.require_email_verification does not lock them out
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
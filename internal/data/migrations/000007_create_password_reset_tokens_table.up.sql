@@ -0,0 +1,15 @@
+-- Create password_reset_tokens table holding SHA-256 hashes of one-time reset tokens
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create index for per-user invalidation and rate limiting of reset emails
+CREATE INDEX idx_password_reset_tokens_user_id_created_at ON password_reset_tokens(user_id, created_at);
+
+-- Create index for stale token cleanup
+CREATE INDEX idx_password_reset_tokens_created_at ON password_reset_tokens(created_at);
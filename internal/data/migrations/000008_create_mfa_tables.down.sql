@@ -0,0 +1,8 @@
+-- Drop mfa_recovery_codes table
+DROP TABLE IF EXISTS mfa_recovery_codes;
+
+-- Drop trigger
+DROP TRIGGER IF EXISTS update_user_mfa_updated_at ON user_mfa;
+
+-- Drop user_mfa table
+DROP TABLE IF EXISTS user_mfa;
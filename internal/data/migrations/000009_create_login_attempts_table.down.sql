@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_login_attempts_last_failure_at;
+
+-- Drop login_attempts table
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,11 @@
+-- Create login_attempts table counting failed logins per account ("email:...")
+-- and per client address ("ip:...")
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,
+    last_failure_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create index for stale record cleanup
+CREATE INDEX idx_login_attempts_last_failure_at ON login_attempts(last_failure_at);
@@ -0,0 +1,8 @@
+-- Remove the API key permission
+DELETE FROM permissions WHERE name = 'api_keys:manage';
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_api_keys_user_id;
+
+-- Drop api_keys table
+DROP TABLE IF EXISTS api_keys;
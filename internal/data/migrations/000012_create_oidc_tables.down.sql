@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_oidc_states_expires_at;
+DROP INDEX IF EXISTS idx_user_identities_user_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS oidc_states;
+DROP TABLE IF EXISTS user_identities;
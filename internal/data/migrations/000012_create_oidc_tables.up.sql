@@ -0,0 +1,32 @@
+-- Create user_identities table linking users to accounts at external
+-- identity providers; subject is the provider's stable user ID ("sub")
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+-- Create index for listing a user's identities
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+
+-- Create oidc_states table holding in-flight social logins; state_hash is
+-- the SHA-256 hash of the state parameter sent to the provider and
+-- binding_hash that of the secret kept in a cookie on the browser that
+-- started the login
+CREATE TABLE IF NOT EXISTS oidc_states (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    state_hash CHAR(64) NOT NULL UNIQUE,
+    binding_hash CHAR(64) NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    nonce VARCHAR(255) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create index for cleanup of expired states
+CREATE INDEX idx_oidc_states_expires_at ON oidc_states(expires_at);
@@ -0,0 +1,17 @@
+-- Remove the OAuth client permission
+DELETE FROM permissions WHERE name = 'oauth_clients:manage';
+
+-- Drop triggers
+DROP TRIGGER IF EXISTS update_oauth_consents_updated_at ON oauth_consents;
+DROP TRIGGER IF EXISTS update_oauth_grants_updated_at ON oauth_grants;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_oauth_grants_expires_at;
+DROP INDEX IF EXISTS idx_oauth_grants_user_id_client_id;
+DROP INDEX IF EXISTS idx_oauth_authorization_codes_expires_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_grants;
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
@@ -0,0 +1,80 @@
+-- Create oauth_clients table for third-party applications; only the
+-- SHA-256 hash of a confidential client's secret is stored
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL DEFAULT '',
+    confidential BOOLEAN NOT NULL DEFAULT FALSE,
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    grant_types TEXT[] NOT NULL DEFAULT '{}',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create oauth_authorization_codes table holding issued, unredeemed codes
+-- with the PKCE challenge they were issued for; redirect_uri_sent records
+-- whether the authorization request named the redirect URI
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code_hash CHAR(64) NOT NULL UNIQUE,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    redirect_uri_sent BOOLEAN NOT NULL DEFAULT FALSE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create oauth_grants table; like sessions, each row is a refresh token
+-- family. The latest refresh token's hash is kept along with the hash of
+-- the one it replaced, since only presenting that again counts as reuse
+CREATE TABLE IF NOT EXISTS oauth_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    refresh_token_hash CHAR(64) NOT NULL,
+    previous_refresh_token_hash CHAR(64) NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create oauth_consents table recording the scopes a user approved per client
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, client_id)
+);
+
+-- Create indexes for cleanup and for revoking a user's grants per client
+CREATE INDEX idx_oauth_authorization_codes_expires_at ON oauth_authorization_codes(expires_at);
+CREATE INDEX idx_oauth_grants_user_id_client_id ON oauth_grants(user_id, client_id);
+CREATE INDEX idx_oauth_grants_expires_at ON oauth_grants(expires_at);
+
+-- Create trigger to automatically update updated_at
+CREATE TRIGGER update_oauth_grants_updated_at
+    BEFORE UPDATE ON oauth_grants
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_oauth_consents_updated_at
+    BEFORE UPDATE ON oauth_consents
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Let admins manage OAuth clients
+INSERT INTO permissions (name, description) VALUES
+    ('oauth_clients:manage', 'Register, list and delete OAuth clients')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name = 'oauth_clients:manage'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_magic_links_created_at;
+DROP INDEX IF EXISTS idx_magic_links_user_id_created_at;
+
+-- Drop magic_links table
+DROP TABLE IF EXISTS magic_links;
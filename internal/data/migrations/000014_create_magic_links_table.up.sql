@@ -0,0 +1,19 @@
+-- Create magic_links table holding passwordless login links; only SHA-256
+-- hashes of the token and of the requesting user agent are stored. Used
+-- links are kept until they no longer count towards the rate limit.
+CREATE TABLE IF NOT EXISTS magic_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    user_agent_hash CHAR(64) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create index for per-user rate limiting and invalidation
+CREATE INDEX idx_magic_links_user_id_created_at ON magic_links(user_id, created_at);
+
+-- Create index for stale link cleanup
+CREATE INDEX idx_magic_links_created_at ON magic_links(created_at);
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a PostgreSQL-backed auth.APIKeyRepository
+func NewAPIKeyRepository(db *sql.DB) auth.APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *auth.APIKey) error {
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO api_keys (id, user_id, name, prefix, secret_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		key.ID, key.UserID, key.Name, key.Prefix, key.SecretHash,
+		pq.Array(key.Scopes), key.ExpiresAt, key.CreatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*auth.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE prefix = $1`
+
+	key := &auth.APIKey{}
+	var scopes pq.StringArray
+	err := r.db.QueryRowContext(ctx, query, prefix).Scan(
+		&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.SecretHash, &scopes,
+		&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrAPIKeyNotFound
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	key.Scopes = scopes
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]*auth.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	keys := make([]*auth.APIKey, 0)
+	for rows.Next() {
+		key := &auth.APIKey{}
+		var scopes pq.StringArray
+		if err := rows.Scan(
+			&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.SecretHash, &scopes,
+			&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt,
+		); err != nil {
+			return nil, apperrors.NewDatabaseError(err)
+		}
+		key.Scopes = scopes
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id, at); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type identityRepository struct {
+	db *sql.DB
+}
+
+// NewIdentityRepository creates a PostgreSQL-backed auth.IdentityRepository
+func NewIdentityRepository(db *sql.DB) auth.IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*auth.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2`
+
+	identity := &auth.Identity{}
+	err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrIdentityNotFound
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return identity, nil
+}
+
+func (r *identityRepository) Create(ctx context.Context, identity *auth.Identity) error {
+	if identity.ID == uuid.Nil {
+		identity.ID = uuid.New()
+	}
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO user_identities (id, user_id, provider, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return auth.ErrIdentityAlreadyLinked
+		}
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *identityRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*auth.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	identities := make([]*auth.Identity, 0)
+	for rows.Next() {
+		identity := &auth.Identity{}
+		if err := rows.Scan(
+			&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt,
+		); err != nil {
+			return nil, apperrors.NewDatabaseError(err)
+		}
+		identities = append(identities, identity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return identities, nil
+}
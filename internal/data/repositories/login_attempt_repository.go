@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepository creates a PostgreSQL-backed auth.LoginAttemptRepository
+func NewLoginAttemptRepository(db *sql.DB) auth.LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Get(ctx context.Context, key string) (*auth.LoginAttempts, error) {
+	query := `
+		SELECT key, failures, locked_until, last_failure_at
+		FROM login_attempts
+		WHERE key = $1`
+
+	attempts := &auth.LoginAttempts{}
+	err := r.db.QueryRowContext(ctx, query, key).Scan(
+		&attempts.Key, &attempts.Failures, &attempts.LockedUntil, &attempts.LastFailureAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &auth.LoginAttempts{Key: key}, nil
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return attempts, nil
+}
+
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	// A single upsert keeps concurrent failures from losing increments
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failure_at < $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`
+
+	var failures int
+	if err := r.db.QueryRowContext(ctx, query, key, at, at.Add(-window)).Scan(&failures); err != nil {
+		return 0, apperrors.NewDatabaseError(err)
+	}
+
+	return failures, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = $2 WHERE key = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, key, until); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	query := `DELETE FROM login_attempts WHERE key = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, key); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *loginAttemptRepository) DeleteStale(ctx context.Context, cutoff time.Time) error {
+	query := `
+		DELETE FROM login_attempts
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < NOW())`
+
+	if _, err := r.db.ExecContext(ctx, query, cutoff); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type magicLinkRepository struct {
+	db *sql.DB
+}
+
+// NewMagicLinkRepository creates a PostgreSQL-backed auth.MagicLinkRepository
+func NewMagicLinkRepository(db *sql.DB) auth.MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
+
+func (r *magicLinkRepository) Create(ctx context.Context, link *auth.MagicLink) error {
+	if link.ID == uuid.Nil {
+		link.ID = uuid.New()
+	}
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO magic_links (id, user_id, token_hash, user_agent_hash, ip_address, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		link.ID, link.UserID, link.TokenHash, link.UserAgentHash, link.IPAddress, link.ExpiresAt, link.CreatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *magicLinkRepository) Consume(ctx context.Context, tokenHash, userAgentHash string) (*auth.MagicLink, error) {
+	// Marking the row used in the same statement that checks it keeps two
+	// concurrent requests from both redeeming the link. A user agent
+	// mismatch leaves the link usable from the right browser.
+	query := `
+		UPDATE magic_links
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND user_agent_hash = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, token_hash, user_agent_hash, ip_address, expires_at, used_at, created_at`
+
+	link := &auth.MagicLink{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash, userAgentHash).Scan(
+		&link.ID, &link.UserID, &link.TokenHash, &link.UserAgentHash, &link.IPAddress,
+		&link.ExpiresAt, &link.UsedAt, &link.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrInvalidMagicLink
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return link, nil
+}
+
+func (r *magicLinkRepository) CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM magic_links WHERE user_id = $1 AND created_at >= $2`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID, since).Scan(&count); err != nil {
+		return 0, apperrors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
+
+func (r *magicLinkRepository) InvalidateByUserID(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE magic_links SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *magicLinkRepository) DeleteStale(ctx context.Context, cutoff time.Time) error {
+	query := `DELETE FROM magic_links WHERE created_at < $1`
+
+	if _, err := r.db.ExecContext(ctx, query, cutoff); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
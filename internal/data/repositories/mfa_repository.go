@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type mfaRepository struct {
+	db *sql.DB
+}
+
+// NewMFARepository creates a PostgreSQL-backed auth.MFARepository
+func NewMFARepository(db *sql.DB) auth.MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*auth.MFAEnrollment, error) {
+	query := `
+		SELECT user_id, secret, enabled_at, last_used_step, created_at, updated_at
+		FROM user_mfa
+		WHERE user_id = $1`
+
+	enrollment := &auth.MFAEnrollment{}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&enrollment.UserID, &enrollment.Secret, &enrollment.EnabledAt, &enrollment.LastUsedStep,
+		&enrollment.CreatedAt, &enrollment.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrMFANotEnabled
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return enrollment, nil
+}
+
+func (r *mfaRepository) Save(ctx context.Context, enrollment *auth.MFAEnrollment) error {
+	now := time.Now()
+	if enrollment.CreatedAt.IsZero() {
+		enrollment.CreatedAt = now
+	}
+	enrollment.UpdatedAt = now
+
+	query := `
+		INSERT INTO user_mfa (user_id, secret, enabled_at, last_used_step, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, enabled_at = EXCLUDED.enabled_at,
+			last_used_step = EXCLUDED.last_used_step, updated_at = EXCLUDED.updated_at`
+
+	_, err := r.db.ExecContext(ctx, query,
+		enrollment.UserID, enrollment.Secret, enrollment.EnabledAt, enrollment.LastUsedStep,
+		enrollment.CreatedAt, enrollment.UpdatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrMFANotEnabled
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) MarkStepUsed(ctx context.Context, userID uuid.UUID, step int64) error {
+	// The comparison in the WHERE clause makes concurrent logins with the
+	// same code race for a single row update
+	query := `
+		UPDATE user_mfa
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2`
+
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	for _, hash := range codeHashes {
+		query := `INSERT INTO mfa_recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3)`
+		if _, err := tx.ExecContext(ctx, query, uuid.New(), userID, hash); err != nil {
+			return apperrors.NewDatabaseError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) ConsumeRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrInvalidMFACode
+	}
+
+	return nil
+}
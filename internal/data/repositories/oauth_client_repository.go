@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type oauthClientRepository struct {
+	db *sql.DB
+}
+
+// NewOAuthClientRepository creates a PostgreSQL-backed auth.OAuthClientRepository
+func NewOAuthClientRepository(db *sql.DB) auth.OAuthClientRepository {
+	return &oauthClientRepository{db: db}
+}
+
+func (r *oauthClientRepository) Create(ctx context.Context, client *auth.OAuthClient) error {
+	if client.ID == uuid.Nil {
+		client.ID = uuid.New()
+	}
+	if client.CreatedAt.IsZero() {
+		client.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO oauth_clients (id, name, secret_hash, confidential, redirect_uris, grant_types, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		client.ID, client.Name, client.SecretHash, client.Confidential,
+		pq.Array(client.RedirectURIs), pq.Array(client.GrantTypes), pq.Array(client.Scopes), client.CreatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *oauthClientRepository) GetByID(ctx context.Context, id uuid.UUID) (*auth.OAuthClient, error) {
+	query := `
+		SELECT id, name, secret_hash, confidential, redirect_uris, grant_types, scopes, created_at
+		FROM oauth_clients
+		WHERE id = $1`
+
+	client := &auth.OAuthClient{}
+	var redirectURIs, grantTypes, scopes pq.StringArray
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&client.ID, &client.Name, &client.SecretHash, &client.Confidential,
+		&redirectURIs, &grantTypes, &scopes, &client.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrOAuthClientNotFound
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	client.RedirectURIs = redirectURIs
+	client.GrantTypes = grantTypes
+	client.Scopes = scopes
+
+	return client, nil
+}
+
+func (r *oauthClientRepository) List(ctx context.Context) ([]*auth.OAuthClient, error) {
+	query := `
+		SELECT id, name, secret_hash, confidential, redirect_uris, grant_types, scopes, created_at
+		FROM oauth_clients
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	clients := make([]*auth.OAuthClient, 0)
+	for rows.Next() {
+		client := &auth.OAuthClient{}
+		var redirectURIs, grantTypes, scopes pq.StringArray
+		if err := rows.Scan(
+			&client.ID, &client.Name, &client.SecretHash, &client.Confidential,
+			&redirectURIs, &grantTypes, &scopes, &client.CreatedAt,
+		); err != nil {
+			return nil, apperrors.NewDatabaseError(err)
+		}
+		client.RedirectURIs = redirectURIs
+		client.GrantTypes = grantTypes
+		client.Scopes = scopes
+		clients = append(clients, client)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return clients, nil
+}
+
+func (r *oauthClientRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM oauth_clients WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrOAuthClientNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type oauthCodeRepository struct {
+	db *sql.DB
+}
+
+// NewOAuthCodeRepository creates a PostgreSQL-backed auth.OAuthCodeRepository
+func NewOAuthCodeRepository(db *sql.DB) auth.OAuthCodeRepository {
+	return &oauthCodeRepository{db: db}
+}
+
+func (r *oauthCodeRepository) Create(ctx context.Context, code *auth.OAuthAuthorizationCode) error {
+	if code.ID == uuid.Nil {
+		code.ID = uuid.New()
+	}
+	if code.CreatedAt.IsZero() {
+		code.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO oauth_authorization_codes (id, code_hash, client_id, user_id, redirect_uri, redirect_uri_sent, scopes, code_challenge, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		code.ID, code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.RedirectURISent,
+		pq.Array(code.Scopes), code.CodeChallenge, code.ExpiresAt, code.CreatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *oauthCodeRepository) Consume(ctx context.Context, codeHash string) (*auth.OAuthAuthorizationCode, error) {
+	// Deleting the row in the statement that reads it keeps a code from
+	// being redeemed twice
+	query := `
+		DELETE FROM oauth_authorization_codes
+		WHERE code_hash = $1 AND expires_at > NOW()
+		RETURNING id, code_hash, client_id, user_id, redirect_uri, redirect_uri_sent, scopes, code_challenge, expires_at, created_at`
+
+	code := &auth.OAuthAuthorizationCode{}
+	var scopes pq.StringArray
+	err := r.db.QueryRowContext(ctx, query, codeHash).Scan(
+		&code.ID, &code.CodeHash, &code.ClientID, &code.UserID, &code.RedirectURI, &code.RedirectURISent,
+		&scopes, &code.CodeChallenge, &code.ExpiresAt, &code.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrInvalidOAuthCode
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	code.Scopes = scopes
+
+	return code, nil
+}
+
+func (r *oauthCodeRepository) DeleteExpired(ctx context.Context) error {
+	query := `DELETE FROM oauth_authorization_codes WHERE expires_at <= NOW()`
+
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type oauthConsentRepository struct {
+	db *sql.DB
+}
+
+// NewOAuthConsentRepository creates a PostgreSQL-backed auth.OAuthConsentRepository
+func NewOAuthConsentRepository(db *sql.DB) auth.OAuthConsentRepository {
+	return &oauthConsentRepository{db: db}
+}
+
+func (r *oauthConsentRepository) Get(ctx context.Context, userID, clientID uuid.UUID) (*auth.OAuthConsent, error) {
+	query := `
+		SELECT oc.user_id, oc.client_id, c.name, oc.scopes, oc.created_at, oc.updated_at
+		FROM oauth_consents oc
+		JOIN oauth_clients c ON c.id = oc.client_id
+		WHERE oc.user_id = $1 AND oc.client_id = $2`
+
+	consent := &auth.OAuthConsent{}
+	var scopes pq.StringArray
+	err := r.db.QueryRowContext(ctx, query, userID, clientID).Scan(
+		&consent.UserID, &consent.ClientID, &consent.ClientName, &scopes, &consent.CreatedAt, &consent.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrOAuthConsentNotFound
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	consent.Scopes = scopes
+
+	return consent, nil
+}
+
+func (r *oauthConsentRepository) Save(ctx context.Context, consent *auth.OAuthConsent) error {
+	now := time.Now()
+	if consent.CreatedAt.IsZero() {
+		consent.CreatedAt = now
+	}
+	if consent.UpdatedAt.IsZero() {
+		consent.UpdatedAt = now
+	}
+
+	query := `
+		INSERT INTO oauth_consents (user_id, client_id, scopes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes`
+
+	_, err := r.db.ExecContext(ctx, query,
+		consent.UserID, consent.ClientID, pq.Array(consent.Scopes), consent.CreatedAt, consent.UpdatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *oauthConsentRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*auth.OAuthConsent, error) {
+	query := `
+		SELECT oc.user_id, oc.client_id, c.name, oc.scopes, oc.created_at, oc.updated_at
+		FROM oauth_consents oc
+		JOIN oauth_clients c ON c.id = oc.client_id
+		WHERE oc.user_id = $1
+		ORDER BY oc.updated_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	consents := make([]*auth.OAuthConsent, 0)
+	for rows.Next() {
+		consent := &auth.OAuthConsent{}
+		var scopes pq.StringArray
+		if err := rows.Scan(
+			&consent.UserID, &consent.ClientID, &consent.ClientName, &scopes, &consent.CreatedAt, &consent.UpdatedAt,
+		); err != nil {
+			return nil, apperrors.NewDatabaseError(err)
+		}
+		consent.Scopes = scopes
+		consents = append(consents, consent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return consents, nil
+}
+
+func (r *oauthConsentRepository) Delete(ctx context.Context, userID, clientID uuid.UUID) error {
+	query := `DELETE FROM oauth_consents WHERE user_id = $1 AND client_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, userID, clientID)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrOAuthConsentNotFound
+	}
+
+	return nil
+}
+
+func (r *oauthConsentRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM oauth_consents WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type oauthGrantRepository struct {
+	db *sql.DB
+}
+
+// NewOAuthGrantRepository creates a PostgreSQL-backed auth.OAuthGrantRepository
+func NewOAuthGrantRepository(db *sql.DB) auth.OAuthGrantRepository {
+	return &oauthGrantRepository{db: db}
+}
+
+func (r *oauthGrantRepository) Create(ctx context.Context, grant *auth.OAuthGrant) error {
+	if grant.ID == uuid.Nil {
+		grant.ID = uuid.New()
+	}
+	now := time.Now()
+	if grant.CreatedAt.IsZero() {
+		grant.CreatedAt = now
+	}
+	if grant.UpdatedAt.IsZero() {
+		grant.UpdatedAt = now
+	}
+
+	query := `
+		INSERT INTO oauth_grants (id, client_id, user_id, scopes, refresh_token_hash, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		grant.ID, grant.ClientID, grant.UserID, pq.Array(grant.Scopes),
+		grant.RefreshTokenHash, grant.ExpiresAt, grant.CreatedAt, grant.UpdatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *oauthGrantRepository) GetByID(ctx context.Context, id uuid.UUID) (*auth.OAuthGrant, error) {
+	query := `
+		SELECT id, client_id, user_id, scopes, refresh_token_hash, previous_refresh_token_hash, expires_at, created_at, updated_at
+		FROM oauth_grants
+		WHERE id = $1`
+
+	grant := &auth.OAuthGrant{}
+	var scopes pq.StringArray
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&grant.ID, &grant.ClientID, &grant.UserID, &scopes,
+		&grant.RefreshTokenHash, &grant.PreviousRefreshTokenHash, &grant.ExpiresAt, &grant.CreatedAt, &grant.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrOAuthGrantNotFound
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	grant.Scopes = scopes
+
+	return grant, nil
+}
+
+func (r *oauthGrantRepository) RotateRefreshToken(ctx context.Context, id uuid.UUID, previousHash, nextHash string) error {
+	query := `
+		UPDATE oauth_grants
+		SET previous_refresh_token_hash = refresh_token_hash, refresh_token_hash = $3, updated_at = NOW()
+		WHERE id = $1 AND refresh_token_hash = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, previousHash, nextHash)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	// The caller just loaded the grant, so a miss means another request
+	// already rotated the token
+	if rowsAffected == 0 {
+		return auth.ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+func (r *oauthGrantRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM oauth_grants WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrOAuthGrantNotFound
+	}
+
+	return nil
+}
+
+func (r *oauthGrantRepository) DeleteByUserAndClient(ctx context.Context, userID, clientID uuid.UUID) error {
+	query := `DELETE FROM oauth_grants WHERE user_id = $1 AND client_id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, clientID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *oauthGrantRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM oauth_grants WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *oauthGrantRepository) DeleteExpired(ctx context.Context) error {
+	query := `DELETE FROM oauth_grants WHERE expires_at <= NOW()`
+
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type oidcStateRepository struct {
+	db *sql.DB
+}
+
+// NewOIDCStateRepository creates a PostgreSQL-backed auth.OIDCStateRepository
+func NewOIDCStateRepository(db *sql.DB) auth.OIDCStateRepository {
+	return &oidcStateRepository{db: db}
+}
+
+func (r *oidcStateRepository) Create(ctx context.Context, state *auth.OIDCState) error {
+	if state.ID == uuid.Nil {
+		state.ID = uuid.New()
+	}
+	if state.CreatedAt.IsZero() {
+		state.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO oidc_states (id, state_hash, binding_hash, provider, nonce, code_verifier, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		state.ID, state.StateHash, state.BindingHash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt, state.CreatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *oidcStateRepository) Consume(ctx context.Context, stateHash, bindingHash string) (*auth.OIDCState, error) {
+	// Deleting the row in the statement that reads it keeps a callback from
+	// being replayed
+	query := `
+		DELETE FROM oidc_states
+		WHERE state_hash = $1 AND binding_hash = $2 AND expires_at > NOW()
+		RETURNING id, state_hash, binding_hash, provider, nonce, code_verifier, expires_at, created_at`
+
+	state := &auth.OIDCState{}
+	err := r.db.QueryRowContext(ctx, query, stateHash, bindingHash).Scan(
+		&state.ID, &state.StateHash, &state.BindingHash, &state.Provider, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt, &state.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrInvalidOIDCState
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return state, nil
+}
+
+func (r *oidcStateRepository) DeleteExpired(ctx context.Context) error {
+	query := `DELETE FROM oidc_states WHERE expires_at <= NOW()`
+
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type passwordResetRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepository creates a PostgreSQL-backed auth.PasswordResetRepository
+func NewPasswordResetRepository(db *sql.DB) auth.PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, token *auth.PasswordResetToken) error {
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *passwordResetRepository) Consume(ctx context.Context, tokenHash string) (*auth.PasswordResetToken, error) {
+	// Marking the row used in the same statement that checks it keeps two
+	// concurrent requests from both redeeming the token
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, token_hash, expires_at, used_at, created_at`
+
+	token := &auth.PasswordResetToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrInvalidResetToken
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return token, nil
+}
+
+func (r *passwordResetRepository) CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM password_reset_tokens WHERE user_id = $1 AND created_at >= $2`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID, since).Scan(&count); err != nil {
+		return 0, apperrors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
+
+func (r *passwordResetRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM password_reset_tokens WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *passwordResetRepository) DeleteStale(ctx context.Context, cutoff time.Time) error {
+	query := `DELETE FROM password_reset_tokens WHERE created_at < $1`
+
+	if _, err := r.db.ExecContext(ctx, query, cutoff); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
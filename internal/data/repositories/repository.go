@@ -1,6 +1,14 @@
 package repositories
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the PostgreSQL error code raised when a UNIQUE constraint fails
+const uniqueViolation = "23505"
 
 type Repository struct {
 	db *sql.DB
@@ -8,4 +16,10 @@ type Repository struct {
 
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
-}
\ No newline at end of file
+}
+
+// isUniqueViolation reports whether err was caused by a UNIQUE constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type roleRepository struct {
+	db *sql.DB
+}
+
+// NewRoleRepository creates a PostgreSQL-backed auth.RoleRepository
+func NewRoleRepository(db *sql.DB) auth.RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]*auth.Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.created_at,
+			COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN permissions p ON p.id = rp.permission_id
+		GROUP BY r.id
+		ORDER BY r.name`
+
+	return r.queryRoles(ctx, query)
+}
+
+func (r *roleRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*auth.Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.created_at,
+			COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN permissions p ON p.id = rp.permission_id
+		WHERE ur.user_id = $1
+		GROUP BY r.id
+		ORDER BY r.name`
+
+	return r.queryRoles(ctx, query, userID)
+}
+
+func (r *roleRepository) Assign(ctx context.Context, userID uuid.UUID, roleName string) error {
+	var roleID uuid.UUID
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = $1`, roleName).Scan(&roleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.ErrRoleNotFound
+		}
+		return apperrors.NewDatabaseError(err)
+	}
+
+	query := `
+		INSERT INTO user_roles (user_id, role_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, role_id) DO NOTHING`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, roleID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *roleRepository) Revoke(ctx context.Context, userID uuid.UUID, roleName string) error {
+	query := `
+		DELETE FROM user_roles ur
+		USING roles r
+		WHERE ur.role_id = r.id AND ur.user_id = $1 AND r.name = $2`
+
+	result, err := r.db.ExecContext(ctx, query, userID, roleName)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrRoleNotAssigned
+	}
+
+	return nil
+}
+
+func (r *roleRepository) queryRoles(ctx context.Context, query string, args ...interface{}) ([]*auth.Role, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	roles := make([]*auth.Role, 0)
+	for rows.Next() {
+		role := &auth.Role{}
+		var permissions pq.StringArray
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt, &permissions); err != nil {
+			return nil, apperrors.NewDatabaseError(err)
+		}
+		role.Permissions = permissions
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return roles, nil
+}
@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+// NewSessionRepository creates a PostgreSQL-backed auth.SessionRepository
+func NewSessionRepository(db *sql.DB) auth.SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *auth.Session) error {
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+	now := time.Now()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = now
+	}
+	if session.UpdatedAt.IsZero() {
+		session.UpdatedAt = now
+	}
+
+	query := `
+		INSERT INTO sessions (id, user_id, refresh_token_hash, user_agent, ip_address, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		session.ID, session.UserID, session.RefreshTokenHash, session.UserAgent,
+		session.IPAddress, session.ExpiresAt, session.CreatedAt, session.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return auth.ErrSessionAlreadyExists
+		}
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*auth.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_token_hash, user_agent, ip_address, expires_at, created_at, updated_at
+		FROM sessions
+		WHERE id = $1`
+
+	return r.scanSession(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *sessionRepository) GetByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (*auth.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_token_hash, user_agent, ip_address, expires_at, created_at, updated_at
+		FROM sessions
+		WHERE refresh_token_hash = $1`
+
+	return r.scanSession(r.db.QueryRowContext(ctx, query, refreshTokenHash))
+}
+
+func (r *sessionRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*auth.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_token_hash, user_agent, ip_address, expires_at, created_at, updated_at
+		FROM sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY updated_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*auth.Session, 0)
+	for rows.Next() {
+		session := &auth.Session{}
+		if err := rows.Scan(
+			&session.ID, &session.UserID, &session.RefreshTokenHash, &session.UserAgent,
+			&session.IPAddress, &session.ExpiresAt, &session.CreatedAt, &session.UpdatedAt,
+		); err != nil {
+			return nil, apperrors.NewDatabaseError(err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionRepository) Update(ctx context.Context, session *auth.Session) error {
+	session.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE sessions
+		SET refresh_token_hash = $2, user_agent = $3, ip_address = $4, expires_at = $5, updated_at = $6
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query,
+		session.ID, session.RefreshTokenHash, session.UserAgent, session.IPAddress,
+		session.ExpiresAt, session.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return auth.ErrSessionAlreadyExists
+		}
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return r.requireAffected(result)
+}
+
+func (r *sessionRepository) RotateRefreshToken(ctx context.Context, id uuid.UUID, previousHash, nextHash string) error {
+	query := `
+		UPDATE sessions
+		SET refresh_token_hash = $3, updated_at = NOW()
+		WHERE id = $1 AND refresh_token_hash = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, previousHash, nextHash)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	// The session exists (the caller just loaded it), so a miss means another
+	// request already rotated the token
+	if rowsAffected == 0 {
+		return auth.ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM sessions WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return r.requireAffected(result)
+}
+
+func (r *sessionRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM sessions WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) DeleteByUserIDExcept(ctx context.Context, userID, keepID uuid.UUID) error {
+	query := `DELETE FROM sessions WHERE user_id = $1 AND id <> $2`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, keepID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) DeleteExpired(ctx context.Context) error {
+	query := `DELETE FROM sessions WHERE expires_at < NOW()`
+
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) scanSession(row *sql.Row) (*auth.Session, error) {
+	session := &auth.Session{}
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.RefreshTokenHash, &session.UserAgent,
+		&session.IPAddress, &session.ExpiresAt, &session.CreatedAt, &session.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrSessionNotFound
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return session, nil
+}
+
+// requireAffected maps an UPDATE/DELETE that touched no rows to auth.ErrSessionNotFound
+func (r *sessionRepository) requireAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrSessionNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type tokenBlacklistRepository struct {
+	db *sql.DB
+}
+
+// NewTokenBlacklistRepository creates a PostgreSQL-backed auth.TokenBlacklist
+func NewTokenBlacklistRepository(db *sql.DB) auth.TokenBlacklist {
+	return &tokenBlacklistRepository{db: db}
+}
+
+func (r *tokenBlacklistRepository) Add(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`
+
+	if _, err := r.db.ExecContext(ctx, query, tokenID, expiresAt); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *tokenBlacklistRepository) Contains(ctx context.Context, tokenID string) (bool, error) {
+	query := `SELECT 1 FROM revoked_tokens WHERE jti = $1 AND expires_at > NOW()`
+
+	var found int
+	err := r.db.QueryRowContext(ctx, query, tokenID).Scan(&found)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, apperrors.NewDatabaseError(err)
+	}
+
+	return true, nil
+}
+
+func (r *tokenBlacklistRepository) DeleteExpired(ctx context.Context) error {
+	query := `DELETE FROM revoked_tokens WHERE expires_at <= NOW()`
+
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type userRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository creates a PostgreSQL-backed auth.UserRepository
+func NewUserRepository(db *sql.DB) auth.UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*auth.User, error) {
+	query := `
+		SELECT id, email, password_hash, is_active, email_verified_at, created_at, updated_at
+		FROM users
+		WHERE email = $1`
+
+	return r.scanUser(r.db.QueryRowContext(ctx, query, email))
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*auth.User, error) {
+	query := `
+		SELECT id, email, password_hash, is_active, email_verified_at, created_at, updated_at
+		FROM users
+		WHERE id = $1`
+
+	return r.scanUser(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *userRepository) Create(ctx context.Context, user *auth.User) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+
+	query := `
+		INSERT INTO users (id, email, password_hash, is_active, email_verified_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		user.ID, user.Email, user.PasswordHash, user.IsActive, user.EmailVerifiedAt,
+		user.CreatedAt, user.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return auth.ErrUserAlreadyExists
+		}
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *auth.User) error {
+	user.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE users
+		SET email = $2, password_hash = $3, is_active = $4, email_verified_at = $5, updated_at = $6
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query,
+		user.ID, user.Email, user.PasswordHash, user.IsActive, user.EmailVerifiedAt, user.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return auth.ErrUserAlreadyExists
+		}
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) scanUser(row *sql.Row) (*auth.User, error) {
+	user := &auth.User{}
+	err := row.Scan(
+		&user.ID, &user.Email, &user.PasswordHash, &user.IsActive, &user.EmailVerifiedAt,
+		&user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type webauthnChallengeRepository struct {
+	db *sql.DB
+}
+
+// NewWebAuthnChallengeRepository creates a PostgreSQL-backed auth.WebAuthnChallengeRepository
+func NewWebAuthnChallengeRepository(db *sql.DB) auth.WebAuthnChallengeRepository {
+	return &webauthnChallengeRepository{db: db}
+}
+
+func (r *webauthnChallengeRepository) Create(ctx context.Context, challenge *auth.WebAuthnChallenge) error {
+	if challenge.ID == uuid.Nil {
+		challenge.ID = uuid.New()
+	}
+	if challenge.CreatedAt.IsZero() {
+		challenge.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO webauthn_challenges (id, challenge_hash, user_id, ceremony, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		challenge.ID, challenge.ChallengeHash, challenge.UserID, challenge.Ceremony, challenge.ExpiresAt, challenge.CreatedAt,
+	)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *webauthnChallengeRepository) Consume(ctx context.Context, challengeHash, ceremony string) (*auth.WebAuthnChallenge, error) {
+	// Deleting the row in the same statement that finds it keeps a
+	// challenge from being answered twice
+	query := `
+		DELETE FROM webauthn_challenges
+		WHERE challenge_hash = $1 AND ceremony = $2 AND expires_at > NOW()
+		RETURNING id, challenge_hash, user_id, ceremony, expires_at, created_at`
+
+	challenge := &auth.WebAuthnChallenge{}
+	err := r.db.QueryRowContext(ctx, query, challengeHash, ceremony).Scan(
+		&challenge.ID, &challenge.ChallengeHash, &challenge.UserID, &challenge.Ceremony,
+		&challenge.ExpiresAt, &challenge.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrInvalidWebAuthnChallenge
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return challenge, nil
+}
+
+func (r *webauthnChallengeRepository) DeleteExpired(ctx context.Context) error {
+	query := `DELETE FROM webauthn_challenges WHERE expires_at < NOW()`
+
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
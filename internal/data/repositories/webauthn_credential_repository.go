@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+type webauthnCredentialRepository struct {
+	db *sql.DB
+}
+
+// NewWebAuthnCredentialRepository creates a PostgreSQL-backed auth.WebAuthnCredentialRepository
+func NewWebAuthnCredentialRepository(db *sql.DB) auth.WebAuthnCredentialRepository {
+	return &webauthnCredentialRepository{db: db}
+}
+
+func (r *webauthnCredentialRepository) Create(ctx context.Context, credential *auth.WebAuthnCredential) error {
+	if credential.ID == uuid.Nil {
+		credential.ID = uuid.New()
+	}
+	if credential.CreatedAt.IsZero() {
+		credential.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO webauthn_credentials (id, user_id, credential_id, public_key, sign_count, name, transports, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		credential.ID, credential.UserID, credential.CredentialID, credential.PublicKey,
+		int64(credential.SignCount), credential.Name, pq.Array(credential.Transports), credential.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return auth.ErrWebAuthnCredentialExists
+		}
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+func (r *webauthnCredentialRepository) GetByCredentialID(ctx context.Context, credentialID []byte) (*auth.WebAuthnCredential, error) {
+	query := `
+		SELECT id, user_id, credential_id, public_key, sign_count, name, transports, last_used_at, created_at
+		FROM webauthn_credentials
+		WHERE credential_id = $1`
+
+	credential := &auth.WebAuthnCredential{}
+	var signCount int64
+	var transports pq.StringArray
+	err := r.db.QueryRowContext(ctx, query, credentialID).Scan(
+		&credential.ID, &credential.UserID, &credential.CredentialID, &credential.PublicKey,
+		&signCount, &credential.Name, &transports, &credential.LastUsedAt, &credential.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrWebAuthnCredentialNotFound
+		}
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	credential.SignCount = uint32(signCount)
+	credential.Transports = transports
+
+	return credential, nil
+}
+
+func (r *webauthnCredentialRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*auth.WebAuthnCredential, error) {
+	query := `
+		SELECT id, user_id, credential_id, public_key, sign_count, name, transports, last_used_at, created_at
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	credentials := make([]*auth.WebAuthnCredential, 0)
+	for rows.Next() {
+		credential := &auth.WebAuthnCredential{}
+		var signCount int64
+		var transports pq.StringArray
+		if err := rows.Scan(
+			&credential.ID, &credential.UserID, &credential.CredentialID, &credential.PublicKey,
+			&signCount, &credential.Name, &transports, &credential.LastUsedAt, &credential.CreatedAt,
+		); err != nil {
+			return nil, apperrors.NewDatabaseError(err)
+		}
+		credential.SignCount = uint32(signCount)
+		credential.Transports = transports
+		credentials = append(credentials, credential)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.NewDatabaseError(err)
+	}
+
+	return credentials, nil
+}
+
+func (r *webauthnCredentialRepository) UpdateSignCount(ctx context.Context, id uuid.UUID, signCount uint32, usedAt time.Time) error {
+	// The counter check is part of the update so concurrent assertions with
+	// the same counter cannot both succeed
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $2, last_used_at = $3
+		WHERE id = $1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0))`
+
+	result, err := r.db.ExecContext(ctx, query, id, int64(signCount), usedAt)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	// The caller just loaded the credential, so a miss means the counter
+	// did not advance
+	if rowsAffected == 0 {
+		return auth.ErrWebAuthnSignCountStale
+	}
+
+	return nil
+}
+
+func (r *webauthnCredentialRepository) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	query := `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return auth.ErrWebAuthnCredentialNotFound
+	}
+
+	return nil
+}
+
+func (r *webauthnCredentialRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM webauthn_credentials WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return apperrors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidAPIKey covers malformed, unknown, expired and revoked keys
+	ErrInvalidAPIKey     = errors.New("invalid API key")
+	ErrAPIKeyNotFound    = errors.New("API key not found")
+	ErrAPIKeysDisabled   = errors.New("API keys are not configured")
+	ErrScopeNotPermitted = errors.New("API key scopes must be permissions the owner holds")
+)
+
+const (
+	// apiKeyPrefix marks API keys so they are recognisable in logs and secret scanners
+	apiKeyPrefix = "ak_"
+	// apiKeyLastUsedInterval limits how often last_used_at is written for a busy key
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// APIKey lets a machine client act as its owning user. Keys look like
+// "ak_<prefix>.<secret>": the prefix is stored in clear to find the key and
+// only a SHA-256 hash of the secret is kept. Scopes limit the key to a subset
+// of the owner's permissions.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	SecretHash string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Active returns true if the key is neither revoked nor expired at now
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || k.ExpiresAt.After(now)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	// List returns every key, newest first
+	List(ctx context.Context) ([]*APIKey, error)
+	// Revoke marks an unrevoked key as revoked, returning ErrAPIKeyNotFound if there is none
+	Revoke(ctx context.Context, id uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+type CreateAPIKeyRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	Name   string    `json:"name" validate:"required,max=100"`
+	Scopes []string  `json:"scopes"`
+	// ExpiresAt is optional; keys without it stay valid until revoked
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is returned once when a key is created. Key is not stored
+// and cannot be retrieved again.
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// WithAPIKeys enables API key authentication for machine clients
+func WithAPIKeys(repo APIKeyRepository) ServiceOption {
+	return func(s *Service) {
+		s.apiKeys = repo
+	}
+}
+
+// CreateAPIKey issues a key acting as req.UserID. Every scope must be a
+// permission the user currently holds.
+func (s *Service) CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	if s.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+
+	user, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	grants, err := s.grantsFor(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range req.Scopes {
+		if !containsString(grants.Permissions, scope) {
+			return nil, ErrScopeNotPermitted
+		}
+	}
+
+	prefix, err := generateAPIKeyPrefix()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := req.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	key := &APIKey{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		Name:       req.Name,
+		Prefix:     prefix,
+		SecretHash: hashToken(secret),
+		Scopes:     scopes,
+		ExpiresAt:  req.ExpiresAt,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.apiKeys.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &CreatedAPIKey{
+		APIKey: key,
+		Key:    apiKeyPrefix + prefix + "." + secret,
+	}, nil
+}
+
+// ListAPIKeys returns every API key without its secret
+func (s *Service) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	if s.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+
+	return s.apiKeys.List(ctx)
+}
+
+// RevokeAPIKey stops a key from authenticating. Revoked keys are kept for auditing.
+func (s *Service) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	if s.apiKeys == nil {
+		return ErrAPIKeysDisabled
+	}
+
+	return s.apiKeys.Revoke(ctx, id)
+}
+
+// AuthenticateAPIKey validates a raw API key and loads its owner. The
+// returned claims carry the key's scopes as permissions but no roles, so a
+// key passes RequirePermission for what it was granted and never passes
+// RequireRole on the strength of its owner's roles.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, rawKey string) (*Authentication, error) {
+	if s.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+
+	prefix, secret, ok := parseAPIKey(rawKey)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeys.GetByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.getUser(ctx, key.UserID)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if !user.IsActive {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Scopes only grant what the owner still holds
+	grants, err := s.grantsFor(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var permissions []string
+	for _, scope := range key.Scopes {
+		if containsString(grants.Permissions, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyLastUsedInterval {
+		if err := s.apiKeys.TouchLastUsed(ctx, key.ID, now); err != nil {
+			return nil, err
+		}
+		key.LastUsedAt = &now
+	}
+
+	return &Authentication{
+		User: user,
+		Claims: &Claims{
+			UserID:      user.ID,
+			Email:       user.Email,
+			TokenType:   APIKeyToken,
+			Permissions: permissions,
+		},
+		APIKey: key,
+	}, nil
+}
+
+// parseAPIKey splits "ak_<prefix>.<secret>" into its parts
+func parseAPIKey(rawKey string) (prefix, secret string, ok bool) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return "", "", false
+	}
+
+	prefix, secret, ok = strings.Cut(strings.TrimPrefix(rawKey, apiKeyPrefix), ".")
+	if !ok || prefix == "" || secret == "" {
+		return "", "", false
+	}
+
+	return prefix, secret, true
+}
+
+// generateAPIKeyPrefix returns a random 8 character lookup prefix
+func generateAPIKeyPrefix() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(base32.StdEncoding.EncodeToString(b)), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBlacklist records revoked token IDs (the "jti" claim) until the token
+// would have expired anyway
+type TokenBlacklist interface {
+	Add(ctx context.Context, tokenID string, expiresAt time.Time) error
+	Contains(ctx context.Context, tokenID string) (bool, error)
+	DeleteExpired(ctx context.Context) error
+}
+
+// MemoryTokenBlacklist is an in-process TokenBlacklist. Entries are lost on
+// restart and not shared between replicas, so it suits development and tests.
+type MemoryTokenBlacklist struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func NewMemoryTokenBlacklist() *MemoryTokenBlacklist {
+	return &MemoryTokenBlacklist{
+		entries: make(map[string]time.Time),
+	}
+}
+
+func (b *MemoryTokenBlacklist) Add(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[tokenID] = expiresAt
+	return nil
+}
+
+func (b *MemoryTokenBlacklist) Contains(ctx context.Context, tokenID string) (bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	expiresAt, ok := b.entries[tokenID]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (b *MemoryTokenBlacklist) DeleteExpired(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	for tokenID, expiresAt := range b.entries {
+		if !now.Before(expiresAt) {
+			delete(b.entries, tokenID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/cache"
+)
+
+// ValidationCache keeps the sessions and users loaded while validating access
+// tokens so authenticated requests do not hit the database every time.
+// Implementations must be safe for concurrent use; a shared store such as
+// Redis can implement it for multi-replica deployments.
+type ValidationCache interface {
+	GetSession(ctx context.Context, id uuid.UUID) (*Session, bool)
+	SetSession(ctx context.Context, session *Session)
+	DeleteSession(ctx context.Context, id uuid.UUID)
+	DeleteUserSessions(ctx context.Context, userID uuid.UUID)
+	GetUser(ctx context.Context, id uuid.UUID) (*User, bool)
+	SetUser(ctx context.Context, user *User)
+	DeleteUser(ctx context.Context, id uuid.UUID)
+}
+
+// MemoryValidationCache is an in-process ValidationCache backed by LRU caches
+type MemoryValidationCache struct {
+	sessions *cache.LRU[uuid.UUID, Session]
+	users    *cache.LRU[uuid.UUID, User]
+}
+
+// NewMemoryValidationCache creates a cache holding up to size sessions and
+// size users, each for at most ttl
+func NewMemoryValidationCache(size int, ttl time.Duration) *MemoryValidationCache {
+	return &MemoryValidationCache{
+		sessions: cache.NewLRU[uuid.UUID, Session](size, ttl),
+		users:    cache.NewLRU[uuid.UUID, User](size, ttl),
+	}
+}
+
+// Values are stored by copy so callers cannot mutate cached entries
+
+func (c *MemoryValidationCache) GetSession(ctx context.Context, id uuid.UUID) (*Session, bool) {
+	session, ok := c.sessions.Get(id)
+	if !ok {
+		return nil, false
+	}
+	return &session, true
+}
+
+func (c *MemoryValidationCache) SetSession(ctx context.Context, session *Session) {
+	c.sessions.Set(session.ID, *session)
+}
+
+func (c *MemoryValidationCache) DeleteSession(ctx context.Context, id uuid.UUID) {
+	c.sessions.Delete(id)
+}
+
+func (c *MemoryValidationCache) DeleteUserSessions(ctx context.Context, userID uuid.UUID) {
+	c.sessions.DeleteFunc(func(_ uuid.UUID, session Session) bool {
+		return session.UserID == userID
+	})
+}
+
+func (c *MemoryValidationCache) GetUser(ctx context.Context, id uuid.UUID) (*User, bool) {
+	user, ok := c.users.Get(id)
+	if !ok {
+		return nil, false
+	}
+	return &user, true
+}
+
+func (c *MemoryValidationCache) SetUser(ctx context.Context, user *User) {
+	c.users.Set(user.ID, *user)
+}
+
+func (c *MemoryValidationCache) DeleteUser(ctx context.Context, id uuid.UUID) {
+	c.users.Delete(id)
+}
+
+// noopValidationCache is used when no cache is configured
+type noopValidationCache struct{}
+
+func (noopValidationCache) GetSession(ctx context.Context, id uuid.UUID) (*Session, bool) {
+	return nil, false
+}
+func (noopValidationCache) SetSession(ctx context.Context, session *Session)         {}
+func (noopValidationCache) DeleteSession(ctx context.Context, id uuid.UUID)          {}
+func (noopValidationCache) DeleteUserSessions(ctx context.Context, userID uuid.UUID) {}
+func (noopValidationCache) GetUser(ctx context.Context, id uuid.UUID) (*User, bool) {
+	return nil, false
+}
+func (noopValidationCache) SetUser(ctx context.Context, user *User)      {}
+func (noopValidationCache) DeleteUser(ctx context.Context, id uuid.UUID) {}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// errMalformedCBOR is returned for input decodeCBOR cannot read
+var errMalformedCBOR = errors.New("malformed CBOR")
+
+// cborMaxDepth bounds nesting so hostile input cannot exhaust the stack.
+// WebAuthn structures nest at most three levels deep.
+const cborMaxDepth = 8
+
+// decodeCBOR decodes the first RFC 8949 data item in data and returns it
+// along with the number of bytes it occupied. Only the definite-length subset
+// WebAuthn authenticators emit is supported: integers (as int64), byte
+// strings ([]byte), text strings (string), arrays ([]interface{}), maps
+// (map[interface{}]interface{}), booleans and null.
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, d.pos, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", errMalformedCBOR)
+	}
+	if d.pos >= len(d.data) {
+		return nil, fmt.Errorf("%w: unexpected end of input", errMalformedCBOR)
+	}
+
+	initial := d.data[d.pos]
+	d.pos++
+	major, info := initial>>5, initial&0x1f
+
+	// Simple values carry no argument worth reading as a length
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("%w: unsupported simple value %d", errMalformedCBOR, info)
+		}
+	}
+
+	arg, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer overflow", errMalformedCBOR)
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer overflow", errMalformedCBOR)
+		}
+		return -1 - int64(arg), nil
+	case 2, 3:
+		b, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		if major == 3 {
+			return string(b), nil
+		}
+		return b, nil
+	case 4:
+		// Every element takes at least one byte, which bounds the allocation
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, fmt.Errorf("%w: array longer than input", errMalformedCBOR)
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, fmt.Errorf("%w: map longer than input", errMalformedCBOR)
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("%w: unsupported map key type", errMalformedCBOR)
+			}
+			if _, dup := m[key]; dup {
+				return nil, fmt.Errorf("%w: duplicate map key", errMalformedCBOR)
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported major type %d", errMalformedCBOR, major)
+	}
+}
+
+// argument reads the integer that follows an initial byte with additional info
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		// 28-30 are reserved and 31 marks indefinite lengths, which
+		// authenticators must not use
+		return 0, fmt.Errorf("%w: unsupported additional info %d", errMalformedCBOR, info)
+	}
+
+	b, err := d.bytes(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+
+	var buf [8]byte
+	copy(buf[8-size:], b)
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+func (d *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: unexpected end of input", errMalformedCBOR)
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
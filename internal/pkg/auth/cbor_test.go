@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string // hex
+		want    interface{}
+		wantLen int
+		wantErr bool
+	}{
+		{name: "small unsigned integer", input: "17", want: int64(23), wantLen: 1},
+		{name: "one byte unsigned integer", input: "1818", want: int64(24), wantLen: 2},
+		{name: "largest int64", input: "1b7fffffffffffffff", want: int64(math.MaxInt64), wantLen: 9},
+		{name: "unsigned integer beyond int64", input: "1bffffffffffffffff", wantErr: true},
+		{name: "negative integer", input: "20", want: int64(-1), wantLen: 1},
+		{name: "COSE RS256 algorithm", input: "390100", want: int64(-257), wantLen: 3},
+		{name: "negative integer beyond int64", input: "3bffffffffffffffff", wantErr: true},
+		{name: "byte string", input: "43010203", want: []byte{1, 2, 3}, wantLen: 4},
+		{name: "text string", input: "63666d74", want: "fmt", wantLen: 4},
+		{name: "array", input: "820102", want: []interface{}{int64(1), int64(2)}, wantLen: 3},
+		{
+			name:    "map with integer and text keys",
+			input:   "a2010263666d74f5",
+			want:    map[interface{}]interface{}{int64(1): int64(2), "fmt": true},
+			wantLen: 8,
+		},
+		{name: "false", input: "f4", want: false, wantLen: 1},
+		{name: "null", input: "f6", want: nil, wantLen: 1},
+		{name: "only the first item is read", input: "0102", want: int64(1), wantLen: 1},
+
+		{name: "empty input", input: "", wantErr: true},
+		{name: "truncated argument", input: "19 01", wantErr: true},
+		{name: "missing argument", input: "18", wantErr: true},
+		{name: "truncated byte string", input: "430102", wantErr: true},
+		{name: "truncated array", input: "8201", wantErr: true},
+		{name: "map key without value", input: "a101", wantErr: true},
+
+		{name: "byte string longer than input", input: "5bffffffffffffffff00", wantErr: true},
+		{name: "text string longer than input", input: "7affffffff00", wantErr: true},
+		{name: "array longer than input", input: "9b000000010000000000", wantErr: true},
+		{name: "map longer than input", input: "bbffffffffffffffff0000", wantErr: true},
+
+		{name: "duplicate integer key", input: "a201000100", wantErr: true},
+		{name: "duplicate text key", input: "a2616100616100", wantErr: true},
+		{name: "byte string key", input: "a1410000", wantErr: true},
+
+		{name: "indefinite length byte string", input: "5f41004101ff", wantErr: true},
+		{name: "reserved additional info", input: "1c", wantErr: true},
+		{name: "tag", input: "c000", wantErr: true},
+		{name: "half precision float", input: "f93c00", wantErr: true},
+		{name: "undefined", input: "f7", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := hex.DecodeString(strings.ReplaceAll(tt.input, " ", ""))
+			if err != nil {
+				t.Fatalf("invalid test input: %v", err)
+			}
+
+			got, n, err := decodeCBOR(input)
+			if tt.wantErr {
+				if !errors.Is(err, errMalformedCBOR) {
+					t.Fatalf("decodeCBOR() error = %v, want %v", err, errMalformedCBOR)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeCBOR() error = %v", err)
+			}
+			if n != tt.wantLen {
+				t.Errorf("decodeCBOR() read %d bytes, want %d", n, tt.wantLen)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCBOR() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORNestingDepth(t *testing.T) {
+	// depth single-element arrays wrapped around an integer
+	nested := func(depth int) []byte {
+		return append(bytes.Repeat([]byte{0x81}, depth), 0x00)
+	}
+
+	if _, n, err := decodeCBOR(nested(cborMaxDepth)); err != nil || n != cborMaxDepth+1 {
+		t.Fatalf("decodeCBOR() at the depth limit = %d, %v", n, err)
+	}
+
+	if _, _, err := decodeCBOR(nested(cborMaxDepth + 1)); !errors.Is(err, errMalformedCBOR) {
+		t.Fatalf("decodeCBOR() past the depth limit error = %v, want %v", err, errMalformedCBOR)
+	}
+
+	// Maps count towards the same limit
+	deepMap := append(bytes.Repeat([]byte{0xa1, 0x01}, cborMaxDepth+1), 0x00)
+	if _, _, err := decodeCBOR(deepMap); !errors.Is(err, errMalformedCBOR) {
+		t.Fatalf("decodeCBOR() of deeply nested maps error = %v, want %v", err, errMalformedCBOR)
+	}
+}
+
+func FuzzDecodeCBOR(f *testing.F) {
+	for _, seed := range []string{"17", "1b7fffffffffffffff", "43010203", "a2010263666d74f5", "8201", "5bffffffffffffffff00", "a201000100"} {
+		input, _ := hex.DecodeString(seed)
+		f.Add(input)
+	}
+	f.Add(noneAttestationFixture(f))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, n, err := decodeCBOR(data)
+		if err != nil {
+			if !errors.Is(err, errMalformedCBOR) {
+				t.Fatalf("decodeCBOR() returned an error that is not errMalformedCBOR: %v", err)
+			}
+			return
+		}
+		if n < 1 || n > len(data) {
+			t.Fatalf("decodeCBOR() read %d bytes of %d", n, len(data))
+		}
+
+		// The item decodes the same without whatever followed it
+		again, m, err := decodeCBOR(data[:n])
+		if err != nil || m != n || !reflect.DeepEqual(again, v) {
+			t.Fatalf("decodeCBOR() of the item alone = %#v, %d, %v, want %#v, %d", again, m, err, v, n)
+		}
+	})
+}
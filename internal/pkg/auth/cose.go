@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers (RFC 9053) offered to authenticators, in order
+// of preference
+const (
+	coseAlgES256 int64 = -7
+	coseAlgEdDSA int64 = -8
+	coseAlgRS256 int64 = -257
+)
+
+// COSE key parameters (RFC 9052 section 7 and RFC 9053 section 7)
+const (
+	coseKeyType      int64 = 1
+	coseKeyAlgorithm int64 = 3
+	coseKeyCurve     int64 = -1
+	coseKeyX         int64 = -2
+	coseKeyY         int64 = -3
+	coseKeyRSAN      int64 = -1
+	coseKeyRSAE      int64 = -2
+
+	coseKtyOKP int64 = 1
+	coseKtyEC2 int64 = 2
+	coseKtyRSA int64 = 3
+
+	coseCrvP256    int64 = 1
+	coseCrvEd25519 int64 = 6
+)
+
+// errUnsupportedCOSEKey is returned for keys that are malformed or use an
+// algorithm other than ES256, EdDSA and RS256
+var errUnsupportedCOSEKey = errors.New("unsupported COSE key")
+
+// coseKey is a credential public key decoded from its COSE_Key encoding
+type coseKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parseCOSEKey decodes a COSE_Key, returning the number of bytes it used so
+// callers can find data that follows it in authenticator data
+func parseCOSEKey(data []byte) (*coseKey, int, error) {
+	v, n, err := decodeCBOR(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: not a map", errUnsupportedCOSEKey)
+	}
+
+	kty, _ := m[coseKeyType].(int64)
+	alg, _ := m[coseKeyAlgorithm].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == coseAlgES256:
+		crv, _ := m[coseKeyCurve].(int64)
+		x, _ := m[coseKeyX].([]byte)
+		y, _ := m[coseKeyY].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("%w: invalid P-256 key", errUnsupportedCOSEKey)
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, fmt.Errorf("%w: point is not on P-256", errUnsupportedCOSEKey)
+		}
+		return &coseKey{alg: alg, key: pub}, n, nil
+
+	case kty == coseKtyOKP && alg == coseAlgEdDSA:
+		crv, _ := m[coseKeyCurve].(int64)
+		x, _ := m[coseKeyX].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("%w: invalid Ed25519 key", errUnsupportedCOSEKey)
+		}
+		return &coseKey{alg: alg, key: ed25519.PublicKey(x)}, n, nil
+
+	case kty == coseKtyRSA && alg == coseAlgRS256:
+		nBytes, _ := m[coseKeyRSAN].([]byte)
+		eBytes, _ := m[coseKeyRSAE].([]byte)
+		if len(nBytes) < 256 || len(eBytes) == 0 || len(eBytes) > 4 {
+			return nil, 0, fmt.Errorf("%w: invalid RSA key", errUnsupportedCOSEKey)
+		}
+		e := new(big.Int).SetBytes(eBytes)
+		return &coseKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: int(e.Int64())}}, n, nil
+	}
+
+	return nil, 0, fmt.Errorf("%w: key type %d with algorithm %d", errUnsupportedCOSEKey, kty, alg)
+}
+
+// verify checks a WebAuthn assertion signature over data
+func (k *coseKey) verify(data, signature []byte) bool {
+	switch pub := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(pub, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// noneAttestationFixtureObject is a "none" attestation object laid out byte
+// for byte as platform authenticators produce it: RP ID "localhost", flags
+// UP|UV|AT, a zero counter and AAGUID, a 16 byte credential ID and an ES256
+// key for noneAttestationFixtureKey, in CTAP2 canonical CBOR.
+const noneAttestationFixtureObject = "o2NmbXRkbm9uZWdhdHRTdG10oGhhdXRoRGF0YViUSZYN5YgOjGh0NBcPZHZgW4_k" +
+	"rrmihjLHmVzzuoMdl2NFAAAAAAAAAAAAAAAAAAAAAAAAAAAAEI8cKn1LbpA1ocTX" +
+	"4vCzloylAQIDJiABIVggoX60GPGFrCfQZG-Q10XWUNbHgMyg-fSsW8UDGsdXwEEi" +
+	"WCDLOsBnztVyVkHAUrhsEXdi1kxKjOu2JFWOUB3xw_UJmQ"
+
+// noneAttestationFixtureKey is the P-256 private scalar of the fixture credential
+const noneAttestationFixtureKey = "4212c68bda37cd1f4e023edcf1c3c2c91f5a8a4362e71f2efbe1f500771f9e79"
+
+func noneAttestationFixture(tb testing.TB) []byte {
+	tb.Helper()
+
+	data, err := webauthnEncoding.DecodeString(noneAttestationFixtureObject)
+	if err != nil {
+		tb.Fatalf("invalid fixture: %v", err)
+	}
+	return data
+}
+
+func noneAttestationFixturePrivateKey(tb testing.TB) *ecdsa.PrivateKey {
+	tb.Helper()
+
+	d, err := hex.DecodeString(noneAttestationFixtureKey)
+	if err != nil {
+		tb.Fatalf("invalid fixture key: %v", err)
+	}
+
+	key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	key.Curve = elliptic.P256()
+	key.X, key.Y = key.Curve.ScalarBaseMult(d)
+	return key
+}
+
+func TestNoneAttestationFixture(t *testing.T) {
+	object := noneAttestationFixture(t)
+
+	decoded, n, err := decodeCBOR(object)
+	if err != nil || n != len(object) {
+		t.Fatalf("decodeCBOR() = %d of %d bytes, %v", n, len(object), err)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		t.Fatalf("attestation object is %T, want a map", decoded)
+	}
+	if attestation["fmt"] != "none" {
+		t.Errorf("fmt = %v, want none", attestation["fmt"])
+	}
+	if statement, _ := attestation["attStmt"].(map[interface{}]interface{}); statement == nil || len(statement) != 0 {
+		t.Errorf("attStmt = %#v, want an empty map", attestation["attStmt"])
+	}
+
+	rawAuthData, _ := attestation["authData"].([]byte)
+	svc := &Service{webauthnConfig: WebAuthnConfig{RPID: "localhost"}}
+	authData, err := svc.parseWebAuthnAuthenticatorData(rawAuthData)
+	if err != nil {
+		t.Fatalf("parseWebAuthnAuthenticatorData() error = %v", err)
+	}
+	if authData.flags != authDataUserPresent|authDataUserVerified|authDataAttestedCredential || authData.signCount != 0 {
+		t.Errorf("flags = %#x, sign count = %d", authData.flags, authData.signCount)
+	}
+	if got := hex.EncodeToString(authData.credentialID); got != "8f1c2a7d4b6e9035a1c4d7e2f0b3968c" {
+		t.Errorf("credential ID = %s", got)
+	}
+
+	key, keyLen, err := parseCOSEKey(authData.publicKey)
+	if err != nil || keyLen != len(authData.publicKey) {
+		t.Fatalf("parseCOSEKey() = %d of %d bytes, %v", keyLen, len(authData.publicKey), err)
+	}
+	if key.alg != coseAlgES256 {
+		t.Errorf("alg = %d, want %d", key.alg, coseAlgES256)
+	}
+
+	// An assertion signed by the credential's private key verifies
+	private := noneAttestationFixturePrivateKey(t)
+	signed := []byte("authenticator data followed by the client data hash")
+	digest := sha256.Sum256(signed)
+	signature, err := ecdsa.SignASN1(rand.Reader, private, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if !key.verify(signed, signature) {
+		t.Error("verify() rejected a signature by the credential key")
+	}
+	if key.verify(append(signed, 0), signature) {
+		t.Error("verify() accepted a signature over other data")
+	}
+
+	other := &Service{webauthnConfig: WebAuthnConfig{RPID: "example.com"}}
+	if _, err := other.parseWebAuthnAuthenticatorData(rawAuthData); !errors.Is(err, ErrInvalidWebAuthnResponse) {
+		t.Errorf("parseWebAuthnAuthenticatorData() for another RP ID error = %v, want %v", err, ErrInvalidWebAuthnResponse)
+	}
+}
+
+func TestParseCOSEKey(t *testing.T) {
+	ecKey := noneAttestationFixturePrivateKey(t)
+	x, y := ecKey.X.FillBytes(make([]byte, 32)), ecKey.Y.FillBytes(make([]byte, 32))
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	rsaN, rsaE := rsaKey.N.Bytes(), big.NewInt(int64(rsaKey.E)).Bytes()
+
+	offCurve := append([]byte(nil), y...)
+	offCurve[31] ^= 1
+
+	tests := []struct {
+		name  string
+		input []byte
+		// sign signs data with the key's private half, when the key is valid
+		sign    func(data []byte) []byte
+		wantErr bool
+	}{
+		{
+			name:  "ES256",
+			input: coseTestKey(coseKeyType, coseKtyEC2, coseKeyAlgorithm, coseAlgES256, coseKeyCurve, coseCrvP256, coseKeyX, x, coseKeyY, y),
+			sign: func(data []byte) []byte {
+				digest := sha256.Sum256(data)
+				signature, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+				return signature
+			},
+		},
+		{
+			name:  "EdDSA",
+			input: coseTestKey(coseKeyType, coseKtyOKP, coseKeyAlgorithm, coseAlgEdDSA, coseKeyCurve, coseCrvEd25519, coseKeyX, []byte(edPublic)),
+			sign:  func(data []byte) []byte { return ed25519.Sign(edPrivate, data) },
+		},
+		{
+			name:  "RS256",
+			input: coseTestKey(coseKeyType, coseKtyRSA, coseKeyAlgorithm, coseAlgRS256, coseKeyRSAN, rsaN, coseKeyRSAE, rsaE),
+			sign: func(data []byte) []byte {
+				digest := sha256.Sum256(data)
+				signature, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+				return signature
+			},
+		},
+		{name: "not a map", input: []byte{0x80}, wantErr: true},
+		{name: "malformed CBOR", input: []byte{0xa5, 0x01}, wantErr: true},
+		{
+			name:    "ES256 on another curve",
+			input:   coseTestKey(coseKeyType, coseKtyEC2, coseKeyAlgorithm, coseAlgES256, coseKeyCurve, int64(2), coseKeyX, x, coseKeyY, y),
+			wantErr: true,
+		},
+		{
+			name:    "ES256 with a short coordinate",
+			input:   coseTestKey(coseKeyType, coseKtyEC2, coseKeyAlgorithm, coseAlgES256, coseKeyCurve, coseCrvP256, coseKeyX, x[1:], coseKeyY, y),
+			wantErr: true,
+		},
+		{
+			name:    "ES256 point not on the curve",
+			input:   coseTestKey(coseKeyType, coseKtyEC2, coseKeyAlgorithm, coseAlgES256, coseKeyCurve, coseCrvP256, coseKeyX, x, coseKeyY, offCurve),
+			wantErr: true,
+		},
+		{
+			name:    "RS256 with a short modulus",
+			input:   coseTestKey(coseKeyType, coseKtyRSA, coseKeyAlgorithm, coseAlgRS256, coseKeyRSAN, rsaN[:128], coseKeyRSAE, rsaE),
+			wantErr: true,
+		},
+		{
+			name:    "unsupported algorithm",
+			input:   coseTestKey(coseKeyType, coseKtyEC2, coseKeyAlgorithm, int64(-35), coseKeyCurve, coseCrvP256, coseKeyX, x, coseKeyY, y),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, n, err := parseCOSEKey(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseCOSEKey() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCOSEKey() error = %v", err)
+			}
+			if n != len(tt.input) {
+				t.Errorf("parseCOSEKey() read %d bytes, want %d", n, len(tt.input))
+			}
+
+			data := []byte("signed data")
+			if !key.verify(data, tt.sign(data)) {
+				t.Error("verify() rejected a valid signature")
+			}
+			if key.verify([]byte("other data"), tt.sign(data)) {
+				t.Error("verify() accepted a signature over other data")
+			}
+		})
+	}
+}
+
+// coseTestKey encodes alternating int64 labels and int64 or []byte values as
+// a CBOR map, in the order given
+func coseTestKey(pairs ...interface{}) []byte {
+	out := cborTestHead(5, uint64(len(pairs)/2))
+	for _, v := range pairs {
+		switch v := v.(type) {
+		case int64:
+			if v >= 0 {
+				out = append(out, cborTestHead(0, uint64(v))...)
+			} else {
+				out = append(out, cborTestHead(1, uint64(-1-v))...)
+			}
+		case []byte:
+			out = append(out, cborTestHead(2, uint64(len(v)))...)
+			out = append(out, v...)
+		}
+	}
+	return out
+}
+
+// cborTestHead encodes the initial byte and argument of a CBOR item
+func cborTestHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	default:
+		return []byte{major<<5 | 25, byte(arg >> 8), byte(arg)}
+	}
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	ErrEmailUnchanged    = errors.New("new email is the same as the current email")
+	// ErrReauthenticationRequired is returned to users without a password or
+	// second factor whose login is too old to confirm a sensitive change
+	ErrReauthenticationRequired = errors.New("log in again to confirm this change")
+)
+
+// recentLoginWindow is how long after logging in a user with neither a
+// password nor a second factor may make changes that would ask for them
+const recentLoginWindow = 10 * time.Minute
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+	// RevokeOtherSessions logs out every other device, keeping the current session
+	RevokeOtherSessions bool `json:"revoke_other_sessions"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email" validate:"required,email"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+}
+
+// ChangePassword replaces the password of the token's user after checking the
+// current one. Outstanding password reset tokens are discarded.
+func (s *Service) ChangePassword(ctx context.Context, claims *Claims, req *ChangePasswordRequest) error {
+	user, err := s.verifyCurrentPassword(ctx, claims, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwordHasher.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hashedPassword
+	if err := s.updateUser(ctx, user); err != nil {
+		return err
+	}
+
+	if req.RevokeOtherSessions {
+		s.cache.DeleteUserSessions(ctx, user.ID)
+		if err := s.sessionRepo.DeleteByUserIDExcept(ctx, user.ID, claims.SessionID); err != nil {
+			return err
+		}
+	}
+
+	if s.passwordResets != nil {
+		if err := s.passwordResets.DeleteByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventPasswordChanged,
+		UserID:     user.ID,
+		SessionID:  claims.SessionID,
+		OccurredAt: time.Now(),
+	})
+
+	return nil
+}
+
+// ChangeEmail starts moving the token's user to a new address. Nothing changes
+// until the token sent to the new address is redeemed through VerifyEmail.
+func (s *Service) ChangeEmail(ctx context.Context, claims *Claims, req *ChangeEmailRequest) error {
+	user, err := s.verifyCurrentPassword(ctx, claims, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(user.Email, req.NewEmail) {
+		return ErrEmailUnchanged
+	}
+
+	// Checked again when the token is redeemed, since the address may be
+	// taken in the meantime
+	if _, err := s.userRepo.GetByEmail(ctx, req.NewEmail); err == nil {
+		return ErrUserAlreadyExists
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+
+	token, err := s.jwtManager.GenerateToken(user.ID, req.NewEmail, EmailChangeToken, s.verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := s.notifier.SendEmailChangeVerification(ctx, user, req.NewEmail, token); err != nil {
+		return err
+	}
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventEmailChangeRequested,
+		UserID:     user.ID,
+		SessionID:  claims.SessionID,
+		Details:    map[string]interface{}{"new_email": req.NewEmail},
+		OccurredAt: time.Now(),
+	})
+
+	return nil
+}
+
+// verifyCurrentPassword loads the token's user and checks password against it
+func (s *Service) verifyCurrentPassword(ctx context.Context, claims *Claims, password string) (*User, error) {
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := s.passwordHasher.VerifyPassword(password, user.PasswordHash)
+	if err != nil || !valid {
+		return nil, ErrIncorrectPassword
+	}
+
+	return user, nil
+}
+
+// reauthenticate loads the token's user and checks they are still the one
+// at the keyboard before a change that grants lasting access to the account:
+// their password if they have one, then their second factor if MFA is
+// enabled. Accounts with neither, such as those created by social login,
+// must have logged in within recentLoginWindow instead.
+func (s *Service) reauthenticate(ctx context.Context, claims *Claims, password, mfaCode string) (*User, error) {
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.enabledMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.PasswordHash == "" && enrollment == nil {
+		session, err := s.sessionRepo.GetByID(ctx, claims.SessionID)
+		if err != nil || time.Since(session.CreatedAt) > recentLoginWindow {
+			return nil, ErrReauthenticationRequired
+		}
+		return user, nil
+	}
+
+	if user.PasswordHash != "" {
+		valid, err := s.passwordHasher.VerifyPassword(password, user.PasswordHash)
+		if err != nil || !valid {
+			return nil, ErrIncorrectPassword
+		}
+	}
+
+	if enrollment != nil {
+		if strings.TrimSpace(mfaCode) == "" {
+			return nil, ErrInvalidMFACode
+		}
+		if err := s.checkMFACode(ctx, enrollment, mfaCode); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReauthenticate(t *testing.T) {
+	hash, err := NewPasswordHasher().HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		passwordHash string
+		loggedInAgo  time.Duration
+		password     string
+		wantErr      error
+	}{
+		{name: "accepts the password", passwordHash: hash, loggedInAgo: time.Hour, password: "correct horse"},
+		{name: "rejects a wrong password", passwordHash: hash, loggedInAgo: time.Minute, password: "wrong", wantErr: ErrIncorrectPassword},
+		{name: "rejects a missing password", passwordHash: hash, loggedInAgo: time.Minute, wantErr: ErrIncorrectPassword},
+		{name: "accepts a recent login without a password", loggedInAgo: time.Minute},
+		{name: "ignores a password sent for an account without one", loggedInAgo: time.Minute, password: "anything"},
+		{name: "rejects an old login without a password", loggedInAgo: recentLoginWindow + time.Minute, wantErr: ErrReauthenticationRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			users := newMemoryUserRepository()
+			sessions := &memorySessionRepository{}
+			svc := NewService(users, sessions, NewJWTManager("test-secret-that-is-long-enough-for-hs256", time.Minute, time.Hour, "test", "test"))
+
+			user := &User{ID: uuid.New(), Email: "jane@example.com", PasswordHash: tt.passwordHash, IsActive: true}
+			users.add(user)
+			session := &Session{ID: uuid.New(), UserID: user.ID, CreatedAt: time.Now().Add(-tt.loggedInAgo)}
+			_ = sessions.Create(ctx, session)
+
+			_, err := svc.reauthenticate(ctx, &Claims{UserID: user.ID, SessionID: session.ID}, tt.password, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("reauthenticate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/logger"
+)
+
+// SecurityEventType identifies a security relevant occurrence
+type SecurityEventType string
+
+const (
+	EventRefreshTokenReuse    SecurityEventType = "refresh_token_reuse"
+	EventPasswordReset        SecurityEventType = "password_reset"
+	EventPasswordChanged      SecurityEventType = "password_changed"
+	EventEmailChangeRequested SecurityEventType = "email_change_requested"
+	EventMFAEnabled           SecurityEventType = "mfa_enabled"
+	EventMFADisabled          SecurityEventType = "mfa_disabled"
+	EventRecoveryCodeUsed     SecurityEventType = "mfa_recovery_code_used"
+	EventRoleAssigned         SecurityEventType = "role_assigned"
+	EventRoleRevoked          SecurityEventType = "role_revoked"
+	EventOAuthConsentGranted  SecurityEventType = "oauth_consent_granted"
+	EventOAuthConsentRevoked  SecurityEventType = "oauth_consent_revoked"
+	// EventNotificationFailed is raised when a token could not be delivered
+	// from the background, where the error cannot reach the requester
+	EventNotificationFailed SecurityEventType = "notification_failed"
+	EventPasskeyAdded       SecurityEventType = "passkey_added"
+	EventPasskeyRemoved     SecurityEventType = "passkey_removed"
+	// EventPasskeyCloned is raised when a passkey's signature counter goes
+	// backwards, meaning its private key has been copied
+	EventPasskeyCloned SecurityEventType = "passkey_cloned"
+)
+
+// SecurityEvent describes something that should be audited or alerted on
+type SecurityEvent struct {
+	Type       SecurityEventType      `json:"type"`
+	UserID     uuid.UUID              `json:"user_id"`
+	SessionID  uuid.UUID              `json:"session_id,omitempty"`
+	IPAddress  string                 `json:"ip_address,omitempty"`
+	UserAgent  string                 `json:"user_agent,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// SecurityEventRecorder receives security events raised by Service
+type SecurityEventRecorder interface {
+	RecordSecurityEvent(ctx context.Context, event SecurityEvent)
+}
+
+type loggerEventRecorder struct {
+	logger logger.Logger
+}
+
+// NewLoggerEventRecorder creates a SecurityEventRecorder that writes events to the application log
+func NewLoggerEventRecorder(log logger.Logger) SecurityEventRecorder {
+	return &loggerEventRecorder{logger: log}
+}
+
+func (r *loggerEventRecorder) RecordSecurityEvent(ctx context.Context, event SecurityEvent) {
+	fields := map[string]interface{}{
+		"security_event": event.Type,
+		"user_id":        event.UserID,
+		"occurred_at":    event.OccurredAt,
+	}
+	if event.SessionID != uuid.Nil {
+		fields["session_id"] = event.SessionID
+	}
+	if event.IPAddress != "" {
+		fields["ip_address"] = event.IPAddress
+	}
+	if event.UserAgent != "" {
+		fields["user_agent"] = event.UserAgent
+	}
+	for k, v := range event.Details {
+		fields[k] = v
+	}
+
+	r.logger.WithFields(fields).Warn("Security event")
+}
+
+type noopEventRecorder struct{}
+
+func (noopEventRecorder) RecordSecurityEvent(ctx context.Context, event SecurityEvent) {}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -19,8 +20,16 @@ var (
 type TokenType string
 
 const (
-	AccessToken  TokenType = "access"
-	RefreshToken TokenType = "refresh"
+	AccessToken            TokenType = "access"
+	RefreshToken           TokenType = "refresh"
+	EmailVerificationToken TokenType = "email_verification"
+	EmailChangeToken       TokenType = "email_change"
+	MFAChallengeToken      TokenType = "mfa_challenge"
+	// APIKeyToken marks claims built from an API key; they are never signed
+	APIKeyToken TokenType = "api_key"
+	// OAuthAccessToken is issued to third-party OAuth clients. It is not
+	// accepted where first-party access tokens are.
+	OAuthAccessToken TokenType = "oauth_access"
 )
 
 type Claims struct {
@@ -28,15 +37,23 @@ type Claims struct {
 	Email     string    `json:"email"`
 	TokenType TokenType `json:"token_type"`
 	SessionID uuid.UUID `json:"session_id"`
+	// Roles and Permissions are only set on access tokens and reflect the
+	// user's grants when the token was issued
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// ClientID and Scope are only set on OAuth access tokens. Scope is the
+	// space separated list of scopes the client was granted.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type JWTManager struct {
-	secret             []byte
-	accessTokenTTL     time.Duration
-	refreshTokenTTL    time.Duration
-	issuer             string
-	audience           string
+	keys            *KeySet
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	issuer          string
+	audience        string
 }
 
 type TokenPair struct {
@@ -45,23 +62,42 @@ type TokenPair struct {
 	ExpiresAt    int64  `json:"expires_at"`
 }
 
+// NewJWTManager creates a JWTManager that signs tokens with HS256 and secret
 func NewJWTManager(secret string, accessTTL, refreshTTL time.Duration, issuer, audience string) *JWTManager {
+	key, _ := NewHMACSigningKey(jwt.SigningMethodHS256.Alg(), secret)
+	return NewJWTManagerWithKey(key, accessTTL, refreshTTL, issuer, audience)
+}
+
+// NewJWTManagerWithKey creates a JWTManager that signs and verifies tokens with key
+func NewJWTManagerWithKey(key *SigningKey, accessTTL, refreshTTL time.Duration, issuer, audience string) *JWTManager {
+	return NewJWTManagerWithKeySet(NewKeySet(key), accessTTL, refreshTTL, issuer, audience)
+}
+
+// NewJWTManagerWithKeySet creates a JWTManager that signs with the current key
+// of keys and verifies with whichever key the token's "kid" header names
+func NewJWTManagerWithKeySet(keys *KeySet, accessTTL, refreshTTL time.Duration, issuer, audience string) *JWTManager {
 	return &JWTManager{
-		secret:             []byte(secret),
-		accessTokenTTL:     accessTTL,
-		refreshTokenTTL:    refreshTTL,
-		issuer:             issuer,
-		audience:           audience,
+		keys:            keys,
+		accessTokenTTL:  accessTTL,
+		refreshTokenTTL: refreshTTL,
+		issuer:          issuer,
+		audience:        audience,
 	}
 }
 
 func (j *JWTManager) GenerateTokenPair(userID uuid.UUID, email string, sessionID uuid.UUID) (*TokenPair, error) {
-	accessToken, err := j.generateToken(userID, email, sessionID, AccessToken, j.accessTokenTTL)
+	return j.GenerateTokenPairWithGrants(userID, email, sessionID, Grants{})
+}
+
+// GenerateTokenPairWithGrants issues a token pair whose access token carries
+// the given roles and permissions
+func (j *JWTManager) GenerateTokenPairWithGrants(userID uuid.UUID, email string, sessionID uuid.UUID, grants Grants) (*TokenPair, error) {
+	accessToken, err := j.generateToken(userID, email, sessionID, AccessToken, j.accessTokenTTL, grants)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := j.generateToken(userID, email, sessionID, RefreshToken, j.refreshTokenTTL)
+	refreshToken, err := j.generateToken(userID, email, sessionID, RefreshToken, j.refreshTokenTTL, Grants{})
 	if err != nil {
 		return nil, err
 	}
@@ -73,11 +109,38 @@ func (j *JWTManager) GenerateTokenPair(userID uuid.UUID, email string, sessionID
 	}, nil
 }
 
-func (j *JWTManager) generateToken(userID uuid.UUID, email string, sessionID uuid.UUID, tokenType TokenType, ttl time.Duration) (string, error) {
+// GenerateToken signs a standalone token of tokenType that is not bound to a session
+func (j *JWTManager) GenerateToken(userID uuid.UUID, email string, tokenType TokenType, ttl time.Duration) (string, error) {
+	return j.generateToken(userID, email, uuid.Nil, tokenType, ttl, Grants{})
+}
+
+// GenerateOAuthAccessToken signs an access token for an OAuth client acting
+// under grantID. Client credentials tokens have no user or grant; their
+// subject is the client itself.
+func (j *JWTManager) GenerateOAuthAccessToken(userID uuid.UUID, email, clientID string, grantID uuid.UUID, scopes []string, ttl time.Duration) (string, error) {
+	claims := j.newClaims(userID, email, grantID, OAuthAccessToken, ttl)
+	claims.ClientID = clientID
+	claims.Scope = strings.Join(scopes, " ")
+	if userID == uuid.Nil {
+		claims.Subject = clientID
+	}
+
+	return j.sign(claims)
+}
+
+func (j *JWTManager) generateToken(userID uuid.UUID, email string, sessionID uuid.UUID, tokenType TokenType, ttl time.Duration, grants Grants) (string, error) {
+	claims := j.newClaims(userID, email, sessionID, tokenType, ttl)
+	claims.Roles = grants.Roles
+	claims.Permissions = grants.Permissions
+
+	return j.sign(claims)
+}
+
+func (j *JWTManager) newClaims(userID uuid.UUID, email string, sessionID uuid.UUID, tokenType TokenType, ttl time.Duration) Claims {
 	now := time.Now()
 	expiresAt := now.Add(ttl)
 
-	claims := Claims{
+	return Claims{
 		UserID:    userID,
 		Email:     email,
 		TokenType: tokenType,
@@ -92,18 +155,24 @@ func (j *JWTManager) generateToken(userID uuid.UUID, email string, sessionID uui
 			ID:        uuid.New().String(),
 		},
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secret)
+// sign signs claims with the current key, naming it in the "kid" header
+func (j *JWTManager) sign(claims Claims) (string, error) {
+	key := j.keys.Current()
+	if !key.CanSign() {
+		return "", ErrMissingSigningKey
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.PrivateKey)
 }
 
 func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return j.secret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.verificationKey)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -129,6 +198,28 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// KeySet returns the keys used to sign and verify tokens
+func (j *JWTManager) KeySet() *KeySet {
+	return j.keys
+}
+
+// verificationKey resolves the key named by the token's "kid" header. Tokens
+// without a kid are checked against the current key.
+func (j *JWTManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	key := j.keys.Current()
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = j.keys.Lookup(kid); !ok {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrInvalidToken
+	}
+
+	return key.PublicKey, nil
+}
+
 func (j *JWTManager) RefreshAccessToken(refreshTokenString string, sessionID uuid.UUID) (*TokenPair, error) {
 	claims, err := j.ValidateToken(refreshTokenString)
 	if err != nil {
@@ -160,4 +251,4 @@ func (j *JWTManager) ExtractSessionID(tokenString string) (uuid.UUID, error) {
 		return uuid.Nil, err
 	}
 	return claims.SessionID, nil
-}
\ No newline at end of file
+}
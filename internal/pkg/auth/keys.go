@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrMissingSigningKey    = errors.New("no private key configured for signing")
+)
+
+// SigningKey pairs a JWT signing method with the keys used to sign and verify.
+// For HMAC both keys are the shared secret; for RSA and ECDSA the private key
+// may be nil when tokens only need to be verified.
+type SigningKey struct {
+	// ID is the "kid" header value; the RFC 7638 thumbprint for RSA and ECDSA
+	// keys and empty for HMAC secrets
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey interface{}
+	PublicKey  interface{}
+}
+
+// NewHMACSigningKey creates an HS256/HS384/HS512 signing key from a shared secret
+func NewHMACSigningKey(algorithm, secret string) (*SigningKey, error) {
+	method, ok := jwt.GetSigningMethod(algorithm).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	return &SigningKey{
+		Method:     method,
+		PrivateKey: []byte(secret),
+		PublicKey:  []byte(secret),
+	}, nil
+}
+
+// LoadSigningKey builds the signing key for algorithm. HMAC algorithms use
+// secret; RSA and ECDSA algorithms read PEM encoded keys from the given paths.
+// When publicKeyPath is empty the public key is derived from the private key.
+func LoadSigningKey(algorithm, secret, privateKeyPath, publicKeyPath string) (*SigningKey, error) {
+	if strings.HasPrefix(algorithm, "HS") {
+		return NewHMACSigningKey(algorithm, secret)
+	}
+
+	method := jwt.GetSigningMethod(algorithm)
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	key := &SigningKey{Method: method}
+
+	if privateKeyPath != "" {
+		privateKey, err := loadPrivateKey(method, privateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey = privateKey
+		key.PublicKey = privateKey.Public()
+	}
+
+	if publicKeyPath != "" {
+		publicKey, err := loadPublicKey(method, publicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey = publicKey
+	}
+
+	if key.PublicKey == nil {
+		return nil, fmt.Errorf("algorithm %s requires a private or public key file", algorithm)
+	}
+
+	if err := checkCurve(method, key.PublicKey); err != nil {
+		return nil, err
+	}
+
+	key.ID = keyThumbprint(key.PublicKey)
+	return key, nil
+}
+
+// CanSign reports whether the key holds private material
+func (k *SigningKey) CanSign() bool {
+	return k.PrivateKey != nil
+}
+
+func loadPrivateKey(method jwt.SigningMethod, path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA private key: %w", err)
+		}
+		return key, nil
+	default:
+		key, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ECDSA private key: %w", err)
+		}
+		return key, nil
+	}
+}
+
+func loadPublicKey(method jwt.SigningMethod, path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA public key: %w", err)
+		}
+		return key, nil
+	default:
+		key, err := jwt.ParseECPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ECDSA public key: %w", err)
+		}
+		return key, nil
+	}
+}
+
+// checkCurve ensures an ECDSA key matches the curve required by its algorithm
+func checkCurve(method jwt.SigningMethod, publicKey interface{}) error {
+	ecMethod, ok := method.(*jwt.SigningMethodECDSA)
+	if !ok {
+		if _, isRSA := publicKey.(*rsa.PublicKey); !isRSA {
+			return fmt.Errorf("algorithm %s requires an RSA key", method.Alg())
+		}
+		return nil
+	}
+
+	ecKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("algorithm %s requires an ECDSA key", method.Alg())
+	}
+
+	if ecKey.Curve.Params().BitSize != ecMethod.CurveBits {
+		return fmt.Errorf("algorithm %s requires a %d-bit curve, got %d-bit", method.Alg(), ecMethod.CurveBits, ecKey.Curve.Params().BitSize)
+	}
+
+	return nil
+}
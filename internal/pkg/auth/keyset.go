@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JSONWebKey is the public part of a signing key in RFC 7517 format
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is the document served from /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// KeySet holds the current signing key plus every key that tokens may still
+// be verified with, indexed by key ID (the JWT "kid" header)
+type KeySet struct {
+	mu      sync.RWMutex
+	current *SigningKey
+	keys    map[string]*keySetEntry
+}
+
+type keySetEntry struct {
+	key *SigningKey
+	// retireAt is zero for keys that never expire
+	retireAt time.Time
+}
+
+// NewKeySet creates a key set that signs with current
+func NewKeySet(current *SigningKey) *KeySet {
+	ks := &KeySet{
+		current: current,
+		keys:    make(map[string]*keySetEntry),
+	}
+	ks.keys[current.ID] = &keySetEntry{key: current}
+	return ks
+}
+
+// Current returns the key new tokens are signed with
+func (ks *KeySet) Current() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.current
+}
+
+// Lookup returns the verification key with the given ID, ignoring retired keys
+func (ks *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	entry, ok := ks.keys[kid]
+	if !ok || entry.retired(time.Now()) {
+		return nil, false
+	}
+	return entry.key, true
+}
+
+// Add registers an additional verification key, e.g. the public key of a
+// previous deployment, that stays valid until it is removed
+func (ks *KeySet) Add(key *SigningKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[key.ID] = &keySetEntry{key: key}
+}
+
+// Rotate makes next the signing key. The previous signing key keeps
+// verifying tokens for retireAfter so tokens issued before the rotation stay
+// valid until they expire.
+func (ks *KeySet) Rotate(next *SigningKey, retireAfter time.Duration) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if previous, ok := ks.keys[ks.current.ID]; ok && ks.current.ID != next.ID {
+		previous.retireAt = time.Now().Add(retireAfter)
+	}
+
+	ks.current = next
+	ks.keys[next.ID] = &keySetEntry{key: next}
+}
+
+// PruneRetired drops keys whose retirement time has passed
+func (ks *KeySet) PruneRetired() {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := time.Now()
+	for kid, entry := range ks.keys {
+		if entry.retired(now) {
+			delete(ks.keys, kid)
+		}
+	}
+}
+
+// JWKS returns the public keys of the set. HMAC keys are never published.
+func (ks *KeySet) JWKS() JSONWebKeySet {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, entry := range ks.keys {
+		if entry.retired(now) {
+			continue
+		}
+		if jwk, ok := entry.key.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
+
+func (e *keySetEntry) retired(now time.Time) bool {
+	return !e.retireAt.IsZero() && now.After(e.retireAt)
+}
+
+// GenerateSigningKey creates a fresh RSA or ECDSA key pair for algorithm
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	method := jwt.GetSigningMethod(algorithm)
+	key := &SigningKey{Method: method}
+
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA:
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey, key.PublicKey = privateKey, &privateKey.PublicKey
+	case *jwt.SigningMethodECDSA:
+		curve, err := curveForBits(m.CurveBits)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey, key.PublicKey = privateKey, &privateKey.PublicKey
+	default:
+		return nil, fmt.Errorf("%w: %s keys cannot be generated", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	key.ID = keyThumbprint(key.PublicKey)
+	return key, nil
+}
+
+// JWK returns the public JSON Web Key for asymmetric keys
+func (k *SigningKey) JWK() (JSONWebKey, bool) {
+	jwk, ok := publicJWK(k.PublicKey)
+	if !ok {
+		return JSONWebKey{}, false
+	}
+
+	jwk.Use = "sig"
+	jwk.Kid = k.ID
+	jwk.Alg = k.Method.Alg()
+	return jwk, true
+}
+
+func publicJWK(publicKey interface{}) (JSONWebKey, bool) {
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return JSONWebKey{
+			Kty: "EC",
+			Crv: pub.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size))),
+		}, true
+	default:
+		return JSONWebKey{}, false
+	}
+}
+
+// PublicKey decodes an RSA or EC JSON Web Key into a public key usable for
+// verifying signatures
+func (k JSONWebKey) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+// keyThumbprint computes the RFC 7638 thumbprint used as key ID
+func keyThumbprint(publicKey interface{}) string {
+	jwk, ok := publicJWK(publicKey)
+	if !ok {
+		return ""
+	}
+
+	// Members must be serialized in lexicographic order without whitespace
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	}
+
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func curveForBits(bits int) (elliptic.Curve, error) {
+	switch bits {
+	case 256:
+		return elliptic.P256(), nil
+	case 384:
+		return elliptic.P384(), nil
+	case 521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported curve size: %d", bits)
+	}
+}
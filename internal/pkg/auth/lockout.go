@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrTooManyAttempts is matched by every *LockoutError
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// LockoutError reports that an account or client address is temporarily locked
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// LoginAttempts is the failure record kept for one account or client address
+type LoginAttempts struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+}
+
+type LoginAttemptRepository interface {
+	// Get returns the record for key, or an empty record if there is none
+	Get(ctx context.Context, key string) (*LoginAttempts, error)
+	// RecordFailure counts a failed attempt and returns the new count. The
+	// count starts over if the previous failure is older than window.
+	RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	// DeleteStale removes records whose last failure is before cutoff and that are not locked
+	DeleteStale(ctx context.Context, cutoff time.Time) error
+}
+
+// LockoutPolicy controls brute-force protection. A threshold of zero disables
+// tracking for that kind of key.
+type LockoutPolicy struct {
+	// AccountThreshold is the number of failures for one email before it is locked
+	AccountThreshold int
+	// IPThreshold is the number of failures from one client address before it is locked
+	IPThreshold int
+	// BaseDuration is the first lockout; each further failure doubles it up to MaxDuration
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+	// Window is how long failures are remembered after the most recent one
+	Window time.Duration
+}
+
+// WithLockout enables brute-force protection for Login and VerifyMFA
+func WithLockout(repo LoginAttemptRepository, policy LockoutPolicy) ServiceOption {
+	return func(s *Service) {
+		s.loginAttempts = repo
+		s.lockout = policy
+	}
+}
+
+// lockoutKey is a tracked key together with the threshold that applies to it
+type lockoutKey struct {
+	key       string
+	threshold int
+}
+
+// lockoutKeys returns the keys a login for email from ipAddress counts against.
+// Unknown emails are tracked too, so lockouts do not reveal which accounts exist.
+func (s *Service) lockoutKeys(email, ipAddress string) []lockoutKey {
+	if s.loginAttempts == nil {
+		return nil
+	}
+
+	var keys []lockoutKey
+	if s.lockout.AccountThreshold > 0 {
+		keys = append(keys, lockoutKey{
+			key:       "email:" + strings.ToLower(strings.TrimSpace(email)),
+			threshold: s.lockout.AccountThreshold,
+		})
+	}
+	if s.lockout.IPThreshold > 0 && ipAddress != "" {
+		keys = append(keys, lockoutKey{
+			key:       "ip:" + ipAddress,
+			threshold: s.lockout.IPThreshold,
+		})
+	}
+
+	return keys
+}
+
+// checkLockout returns a *LockoutError if any of the keys is locked
+func (s *Service) checkLockout(ctx context.Context, keys []lockoutKey) error {
+	now := time.Now()
+	var retryAfter time.Duration
+
+	for _, k := range keys {
+		attempts, err := s.loginAttempts.Get(ctx, k.key)
+		if err != nil {
+			return err
+		}
+		if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+			if wait := attempts.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockoutError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt against every key and locks the
+// keys that reached their threshold
+func (s *Service) recordLoginFailure(ctx context.Context, keys []lockoutKey) error {
+	now := time.Now()
+
+	for _, k := range keys {
+		failures, err := s.loginAttempts.RecordFailure(ctx, k.key, now, s.lockout.Window)
+		if err != nil {
+			return err
+		}
+		if failures < k.threshold {
+			continue
+		}
+
+		if err := s.loginAttempts.Lock(ctx, k.key, now.Add(s.lockoutDuration(failures-k.threshold))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetLoginFailures clears the account counter after a successful login. The
+// address counter is left alone, otherwise an attacker holding one valid
+// account could reset it between guesses against others.
+func (s *Service) resetLoginFailures(ctx context.Context, keys []lockoutKey) error {
+	for _, k := range keys {
+		if strings.HasPrefix(k.key, "email:") {
+			if err := s.loginAttempts.Reset(ctx, k.key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// lockoutDuration doubles BaseDuration for every failure past the threshold
+func (s *Service) lockoutDuration(excess int) time.Duration {
+	d := s.lockout.BaseDuration
+	for i := 0; i < excess && d < s.lockout.MaxDuration; i++ {
+		d *= 2
+	}
+
+	if d > s.lockout.MaxDuration {
+		d = s.lockout.MaxDuration
+	}
+
+	return d
+}
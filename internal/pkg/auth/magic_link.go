@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidMagicLink covers unknown, expired and already used links, and
+	// links redeemed from a different user agent than the one that asked
+	ErrInvalidMagicLink  = errors.New("invalid or expired login link")
+	ErrMagicLinkDisabled = errors.New("magic link login is not configured")
+)
+
+// MagicLink is an outstanding passwordless login. Only SHA-256 hashes of the
+// token and of the requesting user agent are stored.
+type MagicLink struct {
+	ID            uuid.UUID  `json:"id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	TokenHash     string     `json:"-"`
+	UserAgentHash string     `json:"-"`
+	IPAddress     string     `json:"ip_address"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	UsedAt        *time.Time `json:"used_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type MagicLinkRepository interface {
+	Create(ctx context.Context, link *MagicLink) error
+	// Consume marks an unused, unexpired link requested from userAgentHash as
+	// used and returns it, or returns ErrInvalidMagicLink. A link can be
+	// consumed at most once.
+	Consume(ctx context.Context, tokenHash, userAgentHash string) (*MagicLink, error)
+	// CountSince returns how many links were issued to the user at or after since
+	CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	// InvalidateByUserID marks every unused link of the user as used
+	InvalidateByUserID(ctx context.Context, userID uuid.UUID) error
+	// DeleteStale removes links created before cutoff
+	DeleteStale(ctx context.Context, cutoff time.Time) error
+}
+
+// MagicLinkPolicy controls passwordless login links
+type MagicLinkPolicy struct {
+	// TTL is how long a link can be redeemed for
+	TTL time.Duration
+	// RateLimit is the number of links one email can be sent per RateWindow.
+	// Zero disables the limit.
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+type MagicLinkRequest struct {
+	Email     string `json:"email" validate:"required,email"`
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token     string `json:"token" validate:"required"`
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+// WithMagicLinks enables passwordless login through single-use links sent by email
+func WithMagicLinks(repo MagicLinkRepository, policy MagicLinkPolicy) ServiceOption {
+	return func(s *Service) {
+		s.magicLinks = repo
+		s.magicLinkPolicy = policy
+	}
+}
+
+// RequestMagicLink emails a login link to the user with the given email. The
+// link only works from the user agent that asked for it. Unknown and inactive
+// accounts, and emails over the rate limit, are silently ignored, and the
+// lookup and delivery run in the background, so neither the response nor its
+// timing reveals which emails are registered.
+func (s *Service) RequestMagicLink(ctx context.Context, req *MagicLinkRequest) error {
+	if s.magicLinks == nil {
+		return ErrMagicLinkDisabled
+	}
+
+	request := *req
+	s.runInBackground(ctx, "magic_link", func(ctx context.Context) error {
+		return s.sendMagicLink(ctx, &request)
+	})
+
+	return nil
+}
+
+func (s *Service) sendMagicLink(ctx context.Context, req *MagicLinkRequest) error {
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if !user.IsActive {
+		return nil
+	}
+
+	now := time.Now()
+	if limit := s.magicLinkPolicy.RateLimit; limit > 0 {
+		sent, err := s.magicLinks.CountSince(ctx, user.ID, now.Add(-s.magicLinkPolicy.RateWindow))
+		if err != nil {
+			return err
+		}
+		if sent >= limit {
+			return nil
+		}
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	link := &MagicLink{
+		ID:            uuid.New(),
+		UserID:        user.ID,
+		TokenHash:     hashToken(token),
+		UserAgentHash: hashToken(req.UserAgent),
+		IPAddress:     req.IPAddress,
+		ExpiresAt:     now.Add(s.magicLinkPolicy.TTL),
+		CreatedAt:     now,
+	}
+	if err := s.magicLinks.Create(ctx, link); err != nil {
+		return err
+	}
+
+	return s.notifier.SendMagicLink(ctx, user, token)
+}
+
+// LoginWithMagicLink redeems a login link into a session the same way Login
+// does. Users with MFA enabled get a challenge instead of tokens.
+func (s *Service) LoginWithMagicLink(ctx context.Context, req *MagicLinkLoginRequest) (*AuthResponse, error) {
+	if s.magicLinks == nil {
+		return nil, ErrMagicLinkDisabled
+	}
+
+	link, err := s.magicLinks.Consume(ctx, hashToken(req.Token), hashToken(req.UserAgent))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, link.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidMagicLink
+		}
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrInvalidMagicLink
+	}
+
+	// Receiving the link proves the user owns the address
+	if !user.IsEmailVerified() {
+		if err := s.claimUnverifiedEmail(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	// Other links sent to the same inbox are no longer needed
+	if err := s.magicLinks.InvalidateByUserID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	// The link stands in for the password, not for the second factor
+	if challenge, required, err := s.mfaChallenge(ctx, user); err != nil || required {
+		return challenge, err
+	}
+
+	return s.createSessionAndTokens(ctx, user, req.UserAgent, req.IPAddress)
+}
@@ -0,0 +1,422 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor enrollment has not been started")
+	ErrMFADisabled       = errors.New("two-factor authentication is not configured")
+	// ErrInvalidMFACode covers wrong, expired and already used codes
+	ErrInvalidMFACode = errors.New("invalid two-factor code")
+)
+
+// recoveryCodeCount is how many single-use recovery codes a user holds at a time
+const recoveryCodeCount = 10
+
+// MFAEnrollment is a user's TOTP secret. It is pending until the user proves
+// their authenticator works by confirming a code.
+type MFAEnrollment struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	Secret       string     `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Enabled returns true once enrollment has been confirmed
+func (e *MFAEnrollment) Enabled() bool {
+	return e.EnabledAt != nil
+}
+
+type MFARepository interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*MFAEnrollment, error)
+	// Save creates or replaces the user's enrollment
+	Save(ctx context.Context, enrollment *MFAEnrollment) error
+	// Delete removes the enrollment together with its recovery codes
+	Delete(ctx context.Context, userID uuid.UUID) error
+	// MarkStepUsed records that a TOTP time step was used, returning
+	// ErrInvalidMFACode if that step or a later one was used already
+	MarkStepUsed(ctx context.Context, userID uuid.UUID, step int64) error
+	// ReplaceRecoveryCodes discards the user's recovery codes and stores the given hashes
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	// ConsumeRecoveryCode marks an unused code as used, returning ErrInvalidMFACode if there is none
+	ConsumeRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error
+}
+
+// MFASetup is returned when enrollment starts so the user can add the secret
+// to an authenticator app
+type MFASetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type BeginMFAEnrollmentRequest struct {
+	// CurrentPassword is required unless the account has no password
+	CurrentPassword string `json:"current_password"`
+}
+
+type ConfirmMFARequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type DisableMFARequest struct {
+	// CurrentPassword is required unless the account has no password
+	CurrentPassword string `json:"current_password"`
+	// Code is a TOTP code or one of the user's recovery codes
+	Code string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	// Code is a TOTP code or one of the user's recovery codes
+	Code      string `json:"code" validate:"required"`
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+// WithMFA enables TOTP two-factor authentication. issuer is shown in
+// authenticator apps and challengeTTL bounds the time between password and
+// code during login.
+func WithMFA(repo MFARepository, issuer string, challengeTTL time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.mfa = repo
+		s.mfaIssuer = issuer
+		s.mfaChallengeTTL = challengeTTL
+	}
+}
+
+// BeginMFAEnrollment generates a new TOTP secret for the token's user after
+// they reauthenticate, so a stolen access token alone cannot add an
+// attacker's authenticator. Any earlier unconfirmed enrollment is replaced.
+func (s *Service) BeginMFAEnrollment(ctx context.Context, claims *Claims, currentPassword string) (*MFASetup, error) {
+	if s.mfa == nil {
+		return nil, ErrMFADisabled
+	}
+
+	enrollment, err := s.mfa.GetByUserID(ctx, claims.UserID)
+	if err != nil && !errors.Is(err, ErrMFANotEnabled) {
+		return nil, err
+	}
+	if enrollment != nil && enrollment.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	user, err := s.reauthenticate(ctx, claims, currentPassword, "")
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.mfa.Save(ctx, &MFAEnrollment{
+		UserID:    user.ID,
+		Secret:    secret,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &MFASetup{
+		Secret: secret,
+		URI:    TOTPURI(s.mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment turns on MFA once the user proves their authenticator
+// produces valid codes, and returns the user's recovery codes. They are only
+// shown this once.
+func (s *Service) ConfirmMFAEnrollment(ctx context.Context, claims *Claims, code string) ([]string, error) {
+	if s.mfa == nil {
+		return nil, ErrMFADisabled
+	}
+
+	enrollment, err := s.mfa.GetByUserID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnabled) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if enrollment.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := ValidateTOTP(enrollment.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	now := time.Now()
+	enrollment.EnabledAt = &now
+	enrollment.LastUsedStep = step
+	enrollment.UpdatedAt = now
+	if err := s.mfa.Save(ctx, enrollment); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventMFAEnabled,
+		UserID:     claims.UserID,
+		SessionID:  claims.SessionID,
+		OccurredAt: now,
+	})
+
+	return codes, nil
+}
+
+// DisableMFA turns off MFA for the token's user after checking their
+// password and a current code from the second factor being removed
+func (s *Service) DisableMFA(ctx context.Context, claims *Claims, currentPassword, code string) error {
+	if s.mfa == nil {
+		return ErrMFADisabled
+	}
+
+	enrollment, err := s.enabledMFA(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if enrollment == nil {
+		return ErrMFANotEnabled
+	}
+
+	if _, err := s.reauthenticate(ctx, claims, currentPassword, code); err != nil {
+		return err
+	}
+
+	if err := s.mfa.Delete(ctx, claims.UserID); err != nil {
+		return err
+	}
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventMFADisabled,
+		UserID:     claims.UserID,
+		SessionID:  claims.SessionID,
+		OccurredAt: time.Now(),
+	})
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the token's user's recovery codes after
+// checking their password
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, claims *Claims, currentPassword string) ([]string, error) {
+	if s.mfa == nil {
+		return nil, ErrMFADisabled
+	}
+
+	if _, err := s.verifyCurrentPassword(ctx, claims, currentPassword); err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.mfa.GetByUserID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !enrollment.Enabled() {
+		return nil, ErrMFANotEnabled
+	}
+
+	return s.replaceRecoveryCodes(ctx, claims.UserID)
+}
+
+// VerifyMFA completes a login that Login answered with an MFA challenge. The
+// challenge token is spent once a correct code is presented.
+func (s *Service) VerifyMFA(ctx context.Context, req *VerifyMFARequest) (*AuthResponse, error) {
+	if s.mfa == nil {
+		return nil, ErrMFADisabled
+	}
+
+	claims, err := s.jwtManager.ValidateToken(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != MFAChallengeToken {
+		return nil, ErrInvalidToken
+	}
+
+	used, err := s.blacklist.Contains(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		return nil, ErrTokenBlacklist
+	}
+
+	// Wrong codes count against the same lockout as wrong passwords
+	lockoutKeys := s.lockoutKeys(claims.Email, req.IPAddress)
+	if err := s.checkLockout(ctx, lockoutKeys); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		return nil, ErrInvalidCredentials
+	}
+
+	enrollment, err := s.mfa.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !enrollment.Enabled() {
+		return nil, ErrMFANotEnabled
+	}
+
+	if err := s.checkMFACode(ctx, enrollment, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.recordLoginFailure(ctx, lockoutKeys); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.resetLoginFailures(ctx, lockoutKeys); err != nil {
+		return nil, err
+	}
+
+	if err := s.revokeAccessToken(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return s.createSessionAndTokens(ctx, user, req.UserAgent, req.IPAddress)
+}
+
+// mfaChallenge answers a correct password with a short-lived token that
+// VerifyMFA exchanges for a session
+func (s *Service) mfaChallenge(ctx context.Context, user *User) (*AuthResponse, bool, error) {
+	if s.mfa == nil {
+		return nil, false, nil
+	}
+
+	enrollment, err := s.mfa.GetByUserID(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnabled) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	if !enrollment.Enabled() {
+		return nil, false, nil
+	}
+
+	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, MFAChallengeToken, s.mfaChallengeTTL)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &AuthResponse{
+		MFARequired: true,
+		MFAToken:    token,
+	}, true, nil
+}
+
+// enabledMFA returns the user's confirmed enrollment, or nil when MFA is not
+// configured or the user has not turned it on
+func (s *Service) enabledMFA(ctx context.Context, userID uuid.UUID) (*MFAEnrollment, error) {
+	if s.mfa == nil {
+		return nil, nil
+	}
+
+	enrollment, err := s.mfa.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnabled) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !enrollment.Enabled() {
+		return nil, nil
+	}
+
+	return enrollment, nil
+}
+
+// checkMFACode accepts either a TOTP code for an unused time step or an unused recovery code
+func (s *Service) checkMFACode(ctx context.Context, enrollment *MFAEnrollment, code string) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := ValidateTOTP(enrollment.Secret, code, time.Now()); ok {
+		return s.mfa.MarkStepUsed(ctx, enrollment.UserID, step)
+	}
+
+	if len(code) == totpDigits {
+		return ErrInvalidMFACode
+	}
+
+	if err := s.mfa.ConsumeRecoveryCode(ctx, enrollment.UserID, hashToken(normalizeRecoveryCode(code))); err != nil {
+		return err
+	}
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventRecoveryCodeUsed,
+		UserID:     enrollment.UserID,
+		OccurredAt: time.Now(),
+	})
+
+	return nil
+}
+
+// replaceRecoveryCodes generates a fresh set of recovery codes, storing only their hashes
+func (s *Service) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+
+	if err := s.mfa.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code like "k7pq2-xm4ta" with 50 bits of entropy
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	encoded := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+	return encoded[:5] + "-" + encoded[5:], nil
+}
+
+// normalizeRecoveryCode makes recovery codes case and separator insensitive
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
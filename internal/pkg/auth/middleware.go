@@ -7,12 +7,19 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	apperrors "github.com/yantology/golang_template/pkg/errors"
 )
 
 const (
 	UserContextKey    = "user"
 	SessionContextKey = "session"
 	UserIDContextKey  = "user_id"
+	ClaimsContextKey  = "claims"
+	APIKeyContextKey  = "api_key"
+
+	// APIKeyHeader carries an API key; "Authorization: ApiKey <key>" works too
+	APIKeyHeader = "X-API-Key"
 )
 
 type Middleware struct {
@@ -36,7 +43,7 @@ func (m *Middleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		user, session, err := m.authService.ValidateToken(c.Request.Context(), token)
+		authn, err := m.authService.Authenticate(c.Request.Context(), token)
 		if err != nil {
 			status := http.StatusUnauthorized
 			message := "Invalid token"
@@ -50,6 +57,8 @@ func (m *Middleware) RequireAuth() gin.HandlerFunc {
 				message = "Session is invalid or expired"
 			case ErrUserNotFound:
 				message = "User not found"
+			case ErrTokenBlacklist:
+				message = "Token has been revoked"
 			}
 
 			c.JSON(status, gin.H{
@@ -60,9 +69,86 @@ func (m *Middleware) RequireAuth() gin.HandlerFunc {
 		}
 
 		// Set user and session in context
-		c.Set(UserContextKey, user)
-		c.Set(SessionContextKey, session)
-		c.Set(UserIDContextKey, user.ID)
+		m.setAuthentication(c, authn)
+
+		c.Next()
+	}
+}
+
+// RequireAPIKey authenticates machine clients by API key, setting the same
+// context keys as RequireAuth except the session
+func (m *Middleware) RequireAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := m.extractAPIKey(c)
+		if key == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "API key required",
+			})
+			c.Abort()
+			return
+		}
+
+		authn, err := m.authService.AuthenticateAPIKey(c.Request.Context(), key)
+		if err != nil {
+			message := "Invalid API key"
+			if err == ErrAPIKeysDisabled {
+				message = "API keys are not enabled"
+			}
+
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": message,
+			})
+			c.Abort()
+			return
+		}
+
+		m.setAuthentication(c, authn)
+
+		c.Next()
+	}
+}
+
+// RequireOAuthScope authenticates third-party clients by OAuth access token
+// and requires every one of scopes. The user context keys are only set for
+// tokens issued on behalf of a user.
+func (m *Middleware) RequireOAuthScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := m.extractTokenFromHeader(c)
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authorization token required",
+			})
+			c.Abort()
+			return
+		}
+
+		authn, err := m.authService.AuthenticateOAuthToken(c.Request.Context(), token)
+		if err != nil {
+			message := "Invalid token"
+			switch err {
+			case ErrExpiredToken:
+				message = "Token has expired"
+			case ErrTokenBlacklist, ErrInvalidSession:
+				message = "Token has been revoked"
+			case ErrOAuthDisabled:
+				message = "OAuth is not enabled"
+			}
+
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": message,
+			})
+			c.Abort()
+			return
+		}
+
+		for _, scope := range scopes {
+			if !authn.Claims.HasScope(scope) {
+				abortForbidden(c, "Missing scope "+scope)
+				return
+			}
+		}
+
+		m.setAuthentication(c, authn)
 
 		c.Next()
 	}
@@ -76,18 +162,101 @@ func (m *Middleware) OptionalAuth() gin.HandlerFunc {
 			return
 		}
 
-		user, session, err := m.authService.ValidateToken(c.Request.Context(), token)
+		authn, err := m.authService.Authenticate(c.Request.Context(), token)
 		if err == nil {
 			// Set user and session in context only if validation succeeds
-			c.Set(UserContextKey, user)
-			c.Set(SessionContextKey, session)
-			c.Set(UserIDContextKey, user.ID)
+			m.setAuthentication(c, authn)
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole allows the request if the access token carries at least one of
+// roles. It must be chained after RequireAuth.
+func (m *Middleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaimsFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authorization token required",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			if claims.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+
+		abortForbidden(c, "Insufficient role")
+	}
+}
+
+// RequirePermission allows the request only if the access token carries every
+// one of permissions. It must be chained after RequireAuth.
+func (m *Middleware) RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaimsFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authorization token required",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !claims.HasPermission(permission) {
+				abortForbidden(c, "Missing permission "+permission)
+				return
+			}
 		}
 
 		c.Next()
 	}
 }
 
+// abortForbidden stops the request with a 403 for an authenticated caller
+// that lacks the required grants
+func abortForbidden(c *gin.Context, message string) {
+	appErr := apperrors.NewForbiddenError(message)
+	c.JSON(appErr.GetStatusCode(), gin.H{
+		"error": appErr.Message,
+	})
+	c.Abort()
+}
+
+func (m *Middleware) setAuthentication(c *gin.Context, authn *Authentication) {
+	c.Set(ClaimsContextKey, authn.Claims)
+	if authn.User != nil {
+		c.Set(UserContextKey, authn.User)
+		c.Set(UserIDContextKey, authn.User.ID)
+	}
+	if authn.Session != nil {
+		c.Set(SessionContextKey, authn.Session)
+	}
+	if authn.APIKey != nil {
+		c.Set(APIKeyContextKey, authn.APIKey)
+	}
+}
+
+func (m *Middleware) extractAPIKey(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) != 2 || parts[0] != "ApiKey" {
+		return ""
+	}
+
+	return parts[1]
+}
+
 func (m *Middleware) extractTokenFromHeader(c *gin.Context) string {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
@@ -109,7 +278,7 @@ func GetUserFromContext(c *gin.Context) (*User, bool) {
 	if !exists {
 		return nil, false
 	}
-	
+
 	authUser, ok := user.(*User)
 	return authUser, ok
 }
@@ -119,17 +288,37 @@ func GetSessionFromContext(c *gin.Context) (*Session, bool) {
 	if !exists {
 		return nil, false
 	}
-	
+
 	authSession, ok := session.(*Session)
 	return authSession, ok
 }
 
+func GetClaimsFromContext(c *gin.Context) (*Claims, bool) {
+	claims, exists := c.Get(ClaimsContextKey)
+	if !exists {
+		return nil, false
+	}
+
+	authClaims, ok := claims.(*Claims)
+	return authClaims, ok
+}
+
+func GetAPIKeyFromContext(c *gin.Context) (*APIKey, bool) {
+	key, exists := c.Get(APIKeyContextKey)
+	if !exists {
+		return nil, false
+	}
+
+	apiKey, ok := key.(*APIKey)
+	return apiKey, ok
+}
+
 func GetUserIDFromContext(c *gin.Context) (uuid.UUID, bool) {
 	userID, exists := c.Get(UserIDContextKey)
 	if !exists {
 		return uuid.Nil, false
 	}
-	
+
 	id, ok := userID.(uuid.UUID)
 	return id, ok
 }
@@ -141,7 +330,7 @@ func GetUserFromStdContext(ctx context.Context) (*User, bool) {
 	if user == nil {
 		return nil, false
 	}
-	
+
 	authUser, ok := user.(*User)
 	return authUser, ok
 }
@@ -151,17 +340,27 @@ func GetSessionFromStdContext(ctx context.Context) (*Session, bool) {
 	if session == nil {
 		return nil, false
 	}
-	
+
 	authSession, ok := session.(*Session)
 	return authSession, ok
 }
 
+func GetClaimsFromStdContext(ctx context.Context) (*Claims, bool) {
+	claims := ctx.Value(ClaimsContextKey)
+	if claims == nil {
+		return nil, false
+	}
+
+	authClaims, ok := claims.(*Claims)
+	return authClaims, ok
+}
+
 func GetUserIDFromStdContext(ctx context.Context) (uuid.UUID, bool) {
 	userID := ctx.Value(UserIDContextKey)
 	if userID == nil {
 		return uuid.Nil, false
 	}
-	
+
 	id, ok := userID.(uuid.UUID)
 	return id, ok
 }
@@ -174,6 +373,10 @@ func WithSession(ctx context.Context, session *Session) context.Context {
 	return context.WithValue(ctx, SessionContextKey, session)
 }
 
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, ClaimsContextKey, claims)
+}
+
 func WithUserID(ctx context.Context, userID uuid.UUID) context.Context {
 	return context.WithValue(ctx, UserIDContextKey, userID)
-}
\ No newline at end of file
+}
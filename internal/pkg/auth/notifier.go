@@ -0,0 +1,30 @@
+package auth
+
+import "context"
+
+// Notifier delivers the one-time tokens issued by Service to users
+type Notifier interface {
+	SendEmailVerification(ctx context.Context, user *User, token string) error
+	SendPasswordReset(ctx context.Context, user *User, token string) error
+	// SendEmailChangeVerification sends the token to newEmail, not to the user's current address
+	SendEmailChangeVerification(ctx context.Context, user *User, newEmail, token string) error
+	SendMagicLink(ctx context.Context, user *User, token string) error
+}
+
+type noopNotifier struct{}
+
+func (noopNotifier) SendEmailVerification(ctx context.Context, user *User, token string) error {
+	return nil
+}
+
+func (noopNotifier) SendPasswordReset(ctx context.Context, user *User, token string) error {
+	return nil
+}
+
+func (noopNotifier) SendEmailChangeVerification(ctx context.Context, user *User, newEmail, token string) error {
+	return nil
+}
+
+func (noopNotifier) SendMagicLink(ctx context.Context, user *User, token string) error {
+	return nil
+}
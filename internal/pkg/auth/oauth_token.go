@@ -0,0 +1,513 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthClientAuth carries client credentials sent in the request body.
+// Handlers fill it from HTTP Basic authentication when that is used instead.
+type OAuthClientAuth struct {
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// OAuthTokenRequest is a token endpoint request (RFC 6749 section 4)
+type OAuthTokenRequest struct {
+	OAuthClientAuth
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+}
+
+// OAuthTokenResponse is a successful token endpoint response
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthIntrospectionRequest asks about a token (RFC 7662). TokenTypeHint is
+// accepted but not needed: the two token formats are told apart by shape.
+type OAuthIntrospectionRequest struct {
+	OAuthClientAuth
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// OAuthIntrospection describes a token. Inactive tokens only report Active.
+type OAuthIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+}
+
+// OAuthRevocationRequest asks for a token to be revoked (RFC 7009)
+type OAuthRevocationRequest struct {
+	OAuthClientAuth
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// ExchangeOAuthToken implements the token endpoint for the authorization
+// code, client credentials and refresh token grants. Protocol failures are
+// returned as *OAuthError.
+func (s *Service) ExchangeOAuthToken(ctx context.Context, req *OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	if s.oauthClients == nil {
+		return nil, ErrOAuthDisabled
+	}
+
+	client, err := s.authenticateOAuthClient(ctx, req.OAuthClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode, GrantTypeClientCredentials, GrantTypeRefreshToken:
+	case "":
+		return nil, oauthError(OAuthErrInvalidRequest, "grant_type is required")
+	default:
+		return nil, oauthError(OAuthErrUnsupportedGrantType, "")
+	}
+
+	if !client.AllowsGrant(req.GrantType) {
+		return nil, oauthError(OAuthErrUnauthorizedClient, "client may not use the "+req.GrantType+" grant")
+	}
+
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		return s.redeemOAuthCode(ctx, client, req)
+	case GrantTypeClientCredentials:
+		return s.issueOAuthClientToken(client, req.Scope)
+	default:
+		return s.refreshOAuthGrant(ctx, client, req)
+	}
+}
+
+// IntrospectOAuthToken reports whether a token is active. Access tokens can
+// be introspected by any confidential client, refresh tokens only by the
+// client they were issued to.
+func (s *Service) IntrospectOAuthToken(ctx context.Context, req *OAuthIntrospectionRequest) (*OAuthIntrospection, error) {
+	if s.oauthClients == nil {
+		return nil, ErrOAuthDisabled
+	}
+
+	client, err := s.authenticateOAuthClient(ctx, req.OAuthClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential {
+		return nil, oauthError(OAuthErrInvalidClient, "only confidential clients may introspect tokens")
+	}
+
+	if _, _, ok := parseOAuthRefreshToken(req.Token); ok {
+		// Looking a token up must not revoke anything, even a replayed one
+		grant, err := s.oauthRefreshGrant(ctx, client, req.Token, false)
+		if err != nil {
+			return &OAuthIntrospection{Active: false}, nil
+		}
+
+		return &OAuthIntrospection{
+			Active:    true,
+			Scope:     strings.Join(grant.Scopes, " "),
+			ClientID:  grant.ClientID.String(),
+			TokenType: GrantTypeRefreshToken,
+			Exp:       grant.ExpiresAt.Unix(),
+			Iat:       grant.UpdatedAt.Unix(),
+			Sub:       grant.UserID.String(),
+		}, nil
+	}
+
+	authn, err := s.AuthenticateOAuthToken(ctx, req.Token)
+	if err != nil {
+		return &OAuthIntrospection{Active: false}, nil
+	}
+
+	introspection := &OAuthIntrospection{
+		Active:    true,
+		Scope:     authn.Claims.Scope,
+		ClientID:  authn.Claims.ClientID,
+		TokenType: "Bearer",
+		Sub:       authn.Claims.Subject,
+	}
+	if authn.User != nil {
+		introspection.Username = authn.User.Email
+	}
+	if authn.Claims.ExpiresAt != nil {
+		introspection.Exp = authn.Claims.ExpiresAt.Unix()
+	}
+	if authn.Claims.IssuedAt != nil {
+		introspection.Iat = authn.Claims.IssuedAt.Unix()
+	}
+
+	return introspection, nil
+}
+
+// RevokeOAuthToken revokes a token issued to the requesting client. Revoking
+// a refresh token revokes its grant and with it every access token issued
+// under the grant. Unknown tokens are ignored, as RFC 7009 requires.
+func (s *Service) RevokeOAuthToken(ctx context.Context, req *OAuthRevocationRequest) error {
+	if s.oauthClients == nil {
+		return ErrOAuthDisabled
+	}
+
+	client, err := s.authenticateOAuthClient(ctx, req.OAuthClientAuth)
+	if err != nil {
+		return err
+	}
+
+	if _, _, ok := parseOAuthRefreshToken(req.Token); ok {
+		grant, err := s.oauthRefreshGrant(ctx, client, req.Token, true)
+		if err != nil {
+			return nil
+		}
+
+		err = s.oauthGrants.Delete(ctx, grant.ID)
+		if errors.Is(err, ErrOAuthGrantNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	claims, err := s.jwtManager.ValidateToken(req.Token)
+	if err != nil || claims.TokenType != OAuthAccessToken || claims.ClientID != client.ID.String() {
+		return nil
+	}
+
+	return s.revokeAccessToken(ctx, claims)
+}
+
+// AuthenticateOAuthToken validates an access token issued to an OAuth
+// client. User is nil on the result for client credentials tokens.
+func (s *Service) AuthenticateOAuthToken(ctx context.Context, tokenString string) (*Authentication, error) {
+	if s.oauthClients == nil {
+		return nil, ErrOAuthDisabled
+	}
+
+	claims, err := s.jwtManager.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != OAuthAccessToken {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.blacklist.Contains(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenBlacklist
+	}
+
+	// Client credentials tokens have no grant; they live as long as the client
+	if claims.SessionID == uuid.Nil {
+		if _, err := s.getOAuthClient(ctx, claims.ClientID); err != nil {
+			return nil, ErrInvalidToken
+		}
+
+		return &Authentication{Claims: claims}, nil
+	}
+
+	grant, err := s.oauthGrants.GetByID(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, ErrOAuthGrantNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+	if grant.ExpiresAt.Before(time.Now()) {
+		return nil, ErrInvalidSession
+	}
+
+	user, err := s.getUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsActive {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Authentication{
+		User:   user,
+		Claims: claims,
+	}, nil
+}
+
+// HasScope reports whether an OAuth access token was granted scope
+func (c *Claims) HasScope(scope string) bool {
+	return containsString(strings.Fields(c.Scope), scope)
+}
+
+// redeemOAuthCode exchanges an authorization code and its PKCE verifier for
+// a new grant
+func (s *Service) redeemOAuthCode(ctx context.Context, client *OAuthClient, req *OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, oauthError(OAuthErrInvalidRequest, "code and code_verifier are required")
+	}
+
+	code, err := s.oauthCodes.Consume(ctx, hashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, ErrInvalidOAuthCode) {
+			return nil, oauthError(OAuthErrInvalidGrant, ErrInvalidOAuthCode.Error())
+		}
+		return nil, err
+	}
+
+	if code.ClientID != client.ID {
+		return nil, oauthError(OAuthErrInvalidGrant, ErrInvalidOAuthCode.Error())
+	}
+
+	// A redirect URI named in the authorization request must be repeated
+	// exactly (RFC 6749 section 4.1.3)
+	if (code.RedirectURISent || req.RedirectURI != "") && req.RedirectURI != code.RedirectURI {
+		return nil, oauthError(OAuthErrInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(pkceChallenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+		return nil, oauthError(OAuthErrInvalidGrant, "code_verifier does not match the code challenge")
+	}
+
+	user, err := s.getUser(ctx, code.UserID)
+	if err != nil || !user.IsActive {
+		return nil, oauthError(OAuthErrInvalidGrant, "the authorizing user is no longer active")
+	}
+
+	refreshToken, grant, err := s.newOAuthGrant(client, user, code.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.oauthGrants.Create(ctx, grant); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.oauthTokenResponse(client, user, grant.ID, grant.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if client.AllowsGrant(GrantTypeRefreshToken) {
+		resp.RefreshToken = refreshToken
+	}
+
+	return resp, nil
+}
+
+// issueOAuthClientToken issues an access token for the client itself. No
+// refresh token is issued; the client can simply ask again.
+func (s *Service) issueOAuthClientToken(client *OAuthClient, scope string) (*OAuthTokenResponse, error) {
+	scopes, err := s.requestedOAuthScopes(scope, client.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.oauthTokenResponse(client, nil, uuid.Nil, scopes)
+}
+
+// refreshOAuthGrant rotates a grant's refresh token and issues a new access
+// token, optionally for fewer scopes than the grant holds. Presenting the
+// refresh token that was rotated away last revokes the whole grant.
+func (s *Service) refreshOAuthGrant(ctx context.Context, client *OAuthClient, req *OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	grant, err := s.oauthRefreshGrant(ctx, client, req.RefreshToken, true)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := s.requestedOAuthScopes(req.Scope, grant.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUser(ctx, grant.UserID)
+	if err != nil || !user.IsActive {
+		return nil, oauthError(OAuthErrInvalidGrant, "the authorizing user is no longer active")
+	}
+
+	next, err := generateOAuthRefreshToken(grant.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.oauthGrants.RotateRefreshToken(ctx, grant.ID, grant.RefreshTokenHash, hashToken(next)); err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			s.revokeReusedOAuthGrant(ctx, grant)
+			return nil, oauthError(OAuthErrInvalidGrant, ErrRefreshTokenReused.Error())
+		}
+		return nil, err
+	}
+
+	resp, err := s.oauthTokenResponse(client, user, grant.ID, scopes)
+	if err != nil {
+		return nil, err
+	}
+	resp.RefreshToken = next
+
+	return resp, nil
+}
+
+// oauthRefreshGrant finds the live grant a refresh token belongs to. The
+// grant must have been issued to client. When revokeReused is set, the
+// grant's previous refresh token revokes the grant. Any other token that
+// does not match is rejected without touching the grant, since grant IDs are
+// public in the session_id claim of access tokens.
+func (s *Service) oauthRefreshGrant(ctx context.Context, client *OAuthClient, refreshToken string, revokeReused bool) (*OAuthGrant, error) {
+	invalid := oauthError(OAuthErrInvalidGrant, "invalid or expired refresh token")
+
+	grantID, _, ok := parseOAuthRefreshToken(refreshToken)
+	if !ok {
+		return nil, invalid
+	}
+
+	grant, err := s.oauthGrants.GetByID(ctx, grantID)
+	if err != nil {
+		if errors.Is(err, ErrOAuthGrantNotFound) {
+			return nil, invalid
+		}
+		return nil, err
+	}
+
+	if grant.ClientID != client.ID {
+		return nil, invalid
+	}
+
+	presentedHash := hashToken(refreshToken)
+	if subtle.ConstantTimeCompare([]byte(presentedHash), []byte(grant.RefreshTokenHash)) != 1 {
+		reused := grant.PreviousRefreshTokenHash != "" &&
+			subtle.ConstantTimeCompare([]byte(presentedHash), []byte(grant.PreviousRefreshTokenHash)) == 1
+		if reused && revokeReused {
+			s.revokeReusedOAuthGrant(ctx, grant)
+		}
+		return nil, invalid
+	}
+
+	if grant.ExpiresAt.Before(time.Now()) {
+		_ = s.oauthGrants.Delete(ctx, grant.ID)
+		return nil, invalid
+	}
+
+	return grant, nil
+}
+
+// revokeReusedOAuthGrant deletes a grant whose old refresh token was
+// presented again, since the token may have been stolen
+func (s *Service) revokeReusedOAuthGrant(ctx context.Context, grant *OAuthGrant) {
+	_ = s.oauthGrants.Delete(ctx, grant.ID)
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventRefreshTokenReuse,
+		UserID:     grant.UserID,
+		SessionID:  grant.ID,
+		Details:    map[string]interface{}{"client_id": grant.ClientID},
+		OccurredAt: time.Now(),
+	})
+}
+
+// authenticateOAuthClient checks the client's credentials. Confidential
+// clients must present their secret; public clients must not send one.
+func (s *Service) authenticateOAuthClient(ctx context.Context, creds OAuthClientAuth) (*OAuthClient, error) {
+	if creds.ClientID == "" {
+		return nil, oauthError(OAuthErrInvalidClient, "client authentication is required")
+	}
+
+	client, err := s.getOAuthClient(ctx, creds.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.Confidential {
+		if creds.ClientSecret != "" {
+			return nil, oauthError(OAuthErrInvalidClient, "client authentication failed")
+		}
+		return client, nil
+	}
+
+	if creds.ClientSecret == "" ||
+		subtle.ConstantTimeCompare([]byte(hashToken(creds.ClientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthError(OAuthErrInvalidClient, "client authentication failed")
+	}
+
+	return client, nil
+}
+
+// newOAuthGrant builds a grant and the refresh token for it
+func (s *Service) newOAuthGrant(client *OAuthClient, user *User, scopes []string) (string, *OAuthGrant, error) {
+	grantID := uuid.New()
+	refreshToken, err := generateOAuthRefreshToken(grantID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	return refreshToken, &OAuthGrant{
+		ID:               grantID,
+		ClientID:         client.ID,
+		UserID:           user.ID,
+		Scopes:           scopes,
+		RefreshTokenHash: hashToken(refreshToken),
+		ExpiresAt:        now.Add(s.oauthConfig.RefreshTokenTTL),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}, nil
+}
+
+// oauthTokenResponse signs an access token for the client, acting for user
+// under grantID, or for itself when user is nil
+func (s *Service) oauthTokenResponse(client *OAuthClient, user *User, grantID uuid.UUID, scopes []string) (*OAuthTokenResponse, error) {
+	userID, email := uuid.Nil, ""
+	if user != nil {
+		userID, email = user.ID, user.Email
+	}
+
+	accessToken, err := s.jwtManager.GenerateOAuthAccessToken(userID, email, client.ID.String(), grantID, scopes, s.oauthConfig.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.oauthConfig.AccessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// generateOAuthRefreshToken creates a refresh token of the form
+// "<grant ID>.<secret>", so a replayed token can be traced to its grant
+func generateOAuthRefreshToken(grantID uuid.UUID) (string, error) {
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	return grantID.String() + "." + secret, nil
+}
+
+// parseOAuthRefreshToken splits a refresh token into its grant ID and
+// secret. Access tokens, being JWTs, never parse.
+func parseOAuthRefreshToken(token string) (uuid.UUID, string, bool) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" || strings.Contains(secret, ".") {
+		return uuid.Nil, "", false
+	}
+
+	grantID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+
+	return grantID, secret, true
+}
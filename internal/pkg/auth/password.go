@@ -4,12 +4,35 @@ import (
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
 
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// Argon2Params are the Argon2id cost parameters new hashes are created with.
+// Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params returns the parameters used when none are configured
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      64 * 1024, // 64 MB
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
 type PasswordHasher struct {
 	memory      uint32
 	iterations  uint32
@@ -19,12 +42,18 @@ type PasswordHasher struct {
 }
 
 func NewPasswordHasher() *PasswordHasher {
+	return NewPasswordHasherWithParams(DefaultArgon2Params())
+}
+
+// NewPasswordHasherWithParams creates a hasher that hashes with params. It
+// still verifies hashes made with other parameters, and legacy bcrypt hashes.
+func NewPasswordHasherWithParams(params Argon2Params) *PasswordHasher {
 	return &PasswordHasher{
-		memory:      64 * 1024, // 64 MB
-		iterations:  3,
-		parallelism: 2,
-		saltLength:  16,
-		keyLength:   32,
+		memory:      params.Memory,
+		iterations:  params.Iterations,
+		parallelism: params.Parallelism,
+		saltLength:  params.SaltLength,
+		keyLength:   params.KeyLength,
 	}
 }
 
@@ -43,41 +72,91 @@ func (p *PasswordHasher) HashPassword(password string) (string, error) {
 	return fmt.Sprintf(format, argon2.Version, p.memory, p.iterations, p.parallelism, b64Salt, b64Hash), nil
 }
 
+// VerifyPassword checks password against an Argon2id hash or, for users
+// imported from older systems, a bcrypt hash
 func (p *PasswordHasher) VerifyPassword(password, hash string) (bool, error) {
+	if isBcryptHash(hash) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	params, salt, decodedHash, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	comparisonHash := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return subtle.ConstantTimeCompare(decodedHash, comparisonHash) == 1, nil
+}
+
+// NeedsRehash returns true if hash was not made by this hasher's algorithm
+// and parameters, so the password should be hashed again the next time it
+// is known
+func (p *PasswordHasher) NeedsRehash(hash string) bool {
+	params, salt, _, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != p.memory ||
+		params.Iterations != p.iterations ||
+		params.Parallelism != p.parallelism ||
+		params.KeyLength != p.keyLength ||
+		uint32(len(salt)) != p.saltLength
+}
+
+// decodeArgon2Hash parses a hash produced by HashPassword. SaltLength in the
+// returned params is left zero.
+func decodeArgon2Hash(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
 	parts := strings.Split(hash, "$")
-	if len(parts) != 6 {
-		return false, fmt.Errorf("invalid hash format")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("invalid hash format")
 	}
 
 	var version int
 	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
 	if err != nil {
-		return false, err
+		return params, nil, nil, err
 	}
 	if version != argon2.Version {
-		return false, fmt.Errorf("incompatible version of argon2")
+		return params, nil, nil, fmt.Errorf("incompatible version of argon2")
 	}
 
-	var memory, iterations uint32
-	var parallelism uint8
-	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism)
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
 	if err != nil {
-		return false, err
+		return params, nil, nil, err
+	}
+	if params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters")
 	}
 
 	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return false, err
+		return params, nil, nil, err
 	}
 
 	decodedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
 	if err != nil {
-		return false, err
+		return params, nil, nil, err
 	}
+	// An empty key would compare equal to the key derived from any password
+	if len(decodedHash) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid hash format")
+	}
+	params.KeyLength = uint32(len(decodedHash))
 
-	comparisonHash := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(decodedHash)))
+	return params, salt, decodedHash, nil
+}
 
-	return subtle.ConstantTimeCompare(decodedHash, comparisonHash) == 1, nil
+// isBcryptHash reports whether hash is in the modular crypt format bcrypt uses
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
 }
 
 func (p *PasswordHasher) generateRandomBytes(n uint32) ([]byte, error) {
@@ -87,4 +166,4 @@ func (p *PasswordHasher) generateRandomBytes(n uint32) ([]byte, error) {
 		return nil, err
 	}
 	return b, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidResetToken covers unknown, expired and already used reset tokens
+	ErrInvalidResetToken     = errors.New("invalid or expired password reset token")
+	ErrPasswordResetDisabled = errors.New("password reset is not configured")
+)
+
+// PasswordResetToken is an outstanding password reset. Only the SHA-256 hash
+// of the token sent to the user is stored.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *PasswordResetToken) error
+	// Consume marks an unused, unexpired token as used and returns it, or
+	// returns ErrInvalidResetToken. A token can be consumed at most once.
+	Consume(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+	// CountSince returns how many tokens were issued to the user at or after since
+	CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+	// DeleteStale removes tokens created before cutoff
+	DeleteStale(ctx context.Context, cutoff time.Time) error
+}
+
+// PasswordResetPolicy controls password reset tokens
+type PasswordResetPolicy struct {
+	// TTL is how long a token can be redeemed for
+	TTL time.Duration
+	// RateLimit is the number of reset emails one address can be sent per
+	// RateWindow. Zero disables the limit.
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+// WithPasswordReset enables password reset, storing tokens in repo
+func WithPasswordReset(repo PasswordResetRepository, policy PasswordResetPolicy) ServiceOption {
+	return func(s *Service) {
+		s.passwordResets = repo
+		s.passwordResetPolicy = policy
+	}
+}
+
+// RequestPasswordReset sends a reset token to the user with the given email.
+// Unknown and inactive accounts, and emails over the rate limit, are silently
+// ignored, and the lookup and delivery run in the background, so neither the
+// response nor its timing reveals which emails are registered.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	if s.passwordResets == nil {
+		return ErrPasswordResetDisabled
+	}
+
+	s.runInBackground(ctx, "password_reset", func(ctx context.Context) error {
+		return s.sendPasswordReset(ctx, email)
+	})
+
+	return nil
+}
+
+func (s *Service) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if !user.IsActive {
+		return nil
+	}
+
+	now := time.Now()
+	if limit := s.passwordResetPolicy.RateLimit; limit > 0 {
+		sent, err := s.passwordResets.CountSince(ctx, user.ID, now.Add(-s.passwordResetPolicy.RateWindow))
+		if err != nil {
+			return err
+		}
+		if sent >= limit {
+			return nil
+		}
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	reset := &PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(s.passwordResetPolicy.TTL),
+		CreatedAt: now,
+	}
+	if err := s.passwordResets.Create(ctx, reset); err != nil {
+		return err
+	}
+
+	return s.notifier.SendPasswordReset(ctx, user, token)
+}
+
+// ResetPassword sets a new password using a reset token and ends every
+// session of the user, since whoever held them may not have known the new
+// password. If this verifies the address, credentials set up before it was
+// verified are dropped as well.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if s.passwordResets == nil {
+		return ErrPasswordResetDisabled
+	}
+
+	reset, err := s.passwordResets.Consume(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, reset.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if !user.IsActive {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := s.passwordHasher.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hashedPassword
+	// Receiving the token proves the user owns the address
+	claimed := !user.IsEmailVerified()
+	if claimed {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.updateUser(ctx, user); err != nil {
+		return err
+	}
+
+	s.cache.DeleteUserSessions(ctx, user.ID)
+	if err := s.sessionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if claimed {
+		if err := s.dropUnverifiedCredentials(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventPasswordReset,
+		UserID:     user.ID,
+		OccurredAt: time.Now(),
+	})
+
+	// Other outstanding reset tokens would still allow a takeover
+	return s.passwordResets.DeleteByUserID(ctx, user.ID)
+}
+
+// generateOpaqueToken returns a random URL-safe token with 256 bits of entropy
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
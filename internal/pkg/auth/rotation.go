@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/yantology/golang_template/internal/pkg/logger"
+)
+
+// KeySource produces the key that should currently be used for signing
+type KeySource func() (*SigningKey, error)
+
+// FileKeySource re-reads the configured PEM files, so a key replaced on disk
+// (e.g. a rotated Kubernetes secret) is picked up by every replica
+func FileKeySource(algorithm, privateKeyPath, publicKeyPath string) KeySource {
+	return func() (*SigningKey, error) {
+		return LoadSigningKey(algorithm, "", privateKeyPath, publicKeyPath)
+	}
+}
+
+// GeneratedKeySource creates a new key pair on every call. Generated keys only
+// exist in this process, so it suits single-replica deployments.
+func GeneratedKeySource(algorithm string) KeySource {
+	return func() (*SigningKey, error) {
+		return GenerateSigningKey(algorithm)
+	}
+}
+
+// KeyRotator periodically asks a KeySource for the signing key and rotates the
+// KeySet when it changes. Previous keys keep verifying for retireAfter, which
+// should be at least the refresh token TTL.
+type KeyRotator struct {
+	keys        *KeySet
+	source      KeySource
+	interval    time.Duration
+	retireAfter time.Duration
+	logger      logger.Logger
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewKeyRotator(keys *KeySet, source KeySource, interval, retireAfter time.Duration, log logger.Logger) *KeyRotator {
+	return &KeyRotator{
+		keys:        keys,
+		source:      source,
+		interval:    interval,
+		retireAfter: retireAfter,
+		logger:      log,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start runs the rotation loop in the background
+func (r *KeyRotator) Start() {
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.Rotate(); err != nil {
+					r.logger.WithError(err).Error("JWT key rotation failed")
+				}
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the rotation loop
+func (r *KeyRotator) Stop(ctx context.Context) error {
+	close(r.stop)
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Rotate fetches the key from the source, makes it current if it is new and
+// drops keys that have passed their retirement time
+func (r *KeyRotator) Rotate() error {
+	defer r.keys.PruneRetired()
+
+	next, err := r.source()
+	if err != nil {
+		return err
+	}
+
+	if next.ID == r.keys.Current().ID {
+		return nil
+	}
+
+	r.keys.Rotate(next, r.retireAfter)
+	r.logger.WithField("kid", next.ID).Info("Rotated JWT signing key")
+
+	return nil
+}
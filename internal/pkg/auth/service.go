@@ -2,37 +2,56 @@ package auth
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrUserNotFound       = errors.New("user not found")
-	ErrSessionNotFound    = errors.New("session not found")
-	ErrInvalidSession     = errors.New("invalid session")
+	ErrInvalidCredentials   = errors.New("invalid credentials")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrInvalidSession       = errors.New("invalid session")
+	ErrUserAlreadyExists    = errors.New("user already exists")
+	ErrSessionAlreadyExists = errors.New("session already exists")
+	ErrRefreshTokenReused   = errors.New("refresh token has already been used")
+	ErrEmailNotVerified     = errors.New("email address has not been verified")
+	// ErrInvalidVerificationToken covers malformed, expired and already used verification tokens
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	IsActive     bool      `json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              uuid.UUID  `json:"id"`
+	Email           string     `json:"email"`
+	PasswordHash    string     `json:"-"`
+	IsActive        bool       `json:"is_active"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
+// IsEmailVerified returns true once the user has proven they own their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Session is a login on one device. It is also the refresh token family:
+// every refresh token issued for it carries its ID, and only the most recent
+// one (stored as a SHA-256 hash) may be exchanged.
 type Session struct {
-	ID           uuid.UUID `json:"id"`
-	UserID       uuid.UUID `json:"user_id"`
-	RefreshToken string    `json:"-"`
-	UserAgent    string    `json:"user_agent"`
-	IPAddress    string    `json:"ip_address"`
-	ExpiresAt    time.Time `json:"expires_at"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID               uuid.UUID `json:"id"`
+	UserID           uuid.UUID `json:"user_id"`
+	RefreshTokenHash string    `json:"-"`
+	UserAgent        string    `json:"user_agent"`
+	IPAddress        string    `json:"ip_address"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 type UserRepository interface {
@@ -45,18 +64,110 @@ type UserRepository interface {
 type SessionRepository interface {
 	Create(ctx context.Context, session *Session) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Session, error)
-	GetByRefreshToken(ctx context.Context, refreshToken string) (*Session, error)
+	GetByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (*Session, error)
+	// ListByUserID returns the user's unexpired sessions, most recently used first
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*Session, error)
 	Update(ctx context.Context, session *Session) error
+	// RotateRefreshToken replaces the refresh token hash only if it still equals
+	// previousHash, returning ErrRefreshTokenReused otherwise
+	RotateRefreshToken(ctx context.Context, id uuid.UUID, previousHash, nextHash string) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+	// DeleteByUserIDExcept deletes every session of the user other than keepID
+	DeleteByUserIDExcept(ctx context.Context, userID, keepID uuid.UUID) error
 	DeleteExpired(ctx context.Context) error
 }
 
 type Service struct {
-	userRepo      UserRepository
-	sessionRepo   SessionRepository
-	jwtManager    *JWTManager
+	userRepo       UserRepository
+	sessionRepo    SessionRepository
+	jwtManager     *JWTManager
 	passwordHasher *PasswordHasher
+	events         SecurityEventRecorder
+	blacklist      TokenBlacklist
+	cache          ValidationCache
+	notifier       Notifier
+	passwordResets PasswordResetRepository
+	mfa            MFARepository
+	loginAttempts  LoginAttemptRepository
+	roles          RoleRepository
+	apiKeys        APIKeyRepository
+	identities     IdentityRepository
+	oidcStates     OIDCStateRepository
+	oidcProviders  map[string]*OIDCProvider
+	oauthClients   OAuthClientRepository
+	oauthCodes     OAuthCodeRepository
+	oauthGrants    OAuthGrantRepository
+	oauthConsents  OAuthConsentRepository
+	magicLinks     MagicLinkRepository
+
+	webauthnCredentials WebAuthnCredentialRepository
+	webauthnChallenges  WebAuthnChallengeRepository
+
+	requireEmailVerification bool
+	verificationTokenTTL     time.Duration
+	passwordResetPolicy      PasswordResetPolicy
+	mfaIssuer                string
+	mfaChallengeTTL          time.Duration
+	lockout                  LockoutPolicy
+	defaultRole              string
+	oidcStateTTL             time.Duration
+	oauthConfig              OAuthServerConfig
+	magicLinkPolicy          MagicLinkPolicy
+	webauthnConfig           WebAuthnConfig
+
+	// background tracks work started by runInBackground
+	background sync.WaitGroup
+}
+
+// ServiceOption configures optional Service collaborators
+type ServiceOption func(*Service)
+
+// WithTokenBlacklist sets where revoked access tokens are recorded. Defaults
+// to an in-memory blacklist.
+func WithTokenBlacklist(blacklist TokenBlacklist) ServiceOption {
+	return func(s *Service) {
+		s.blacklist = blacklist
+	}
+}
+
+// WithValidationCache caches sessions and users looked up by Authenticate.
+// Entries are invalidated on logout, deactivation and user updates.
+func WithValidationCache(cache ValidationCache) ServiceOption {
+	return func(s *Service) {
+		s.cache = cache
+	}
+}
+
+// WithNotifier sets how verification tokens reach users. By default they are discarded.
+func WithNotifier(notifier Notifier) ServiceOption {
+	return func(s *Service) {
+		s.notifier = notifier
+	}
+}
+
+// WithEmailVerification sets whether users must verify their email address
+// before they can log in and how long verification tokens stay valid
+func WithEmailVerification(required bool, tokenTTL time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.requireEmailVerification = required
+		s.verificationTokenTTL = tokenTTL
+	}
+}
+
+// WithPasswordHasher sets how passwords are hashed. Defaults to Argon2id
+// with DefaultArgon2Params.
+func WithPasswordHasher(hasher *PasswordHasher) ServiceOption {
+	return func(s *Service) {
+		s.passwordHasher = hasher
+	}
+}
+
+// WithSecurityEventRecorder sets where security events such as refresh token reuse are reported
+func WithSecurityEventRecorder(recorder SecurityEventRecorder) ServiceOption {
+	return func(s *Service) {
+		s.events = recorder
+	}
 }
 
 type LoginRequest struct {
@@ -73,25 +184,65 @@ type RegisterRequest struct {
 	IPAddress string `json:"-"`
 }
 
+// AuthResponse is the result of registering or logging in. When the user has
+// MFA enabled, Login returns only MFARequired and MFAToken, which is exchanged
+// for tokens through VerifyMFA.
 type AuthResponse struct {
-	User   *User      `json:"user"`
-	Tokens *TokenPair `json:"tokens"`
+	User        *User      `json:"user,omitempty"`
+	Tokens      *TokenPair `json:"tokens,omitempty"`
+	MFARequired bool       `json:"mfa_required,omitempty"`
+	MFAToken    string     `json:"mfa_token,omitempty"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
 }
 
-func NewService(userRepo UserRepository, sessionRepo SessionRepository, jwtManager *JWTManager) *Service {
-	return &Service{
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Authentication is the result of successfully validating an access token or
+// API key. Session is nil for API keys and OAuth tokens, APIKey is nil for
+// access tokens, and User is nil for OAuth client credentials tokens.
+type Authentication struct {
+	User    *User
+	Session *Session
+	Claims  *Claims
+	APIKey  *APIKey
+}
+
+func NewService(userRepo UserRepository, sessionRepo SessionRepository, jwtManager *JWTManager, opts ...ServiceOption) *Service {
+	s := &Service{
 		userRepo:       userRepo,
 		sessionRepo:    sessionRepo,
 		jwtManager:     jwtManager,
 		passwordHasher: NewPasswordHasher(),
+		events:         noopEventRecorder{},
+		blacklist:      NewMemoryTokenBlacklist(),
+		cache:          noopValidationCache{},
+		notifier:       noopNotifier{},
+
+		verificationTokenTTL: 24 * time.Hour,
+		passwordResetPolicy:  PasswordResetPolicy{TTL: time.Hour},
+		mfaIssuer:            "Golang Template",
+		mfaChallengeTTL:      5 * time.Minute,
+		oidcStateTTL:         10 * time.Minute,
+		magicLinkPolicy:      MagicLinkPolicy{TTL: 15 * time.Minute},
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
 func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResponse, error) {
 	// Check if user already exists
 	existingUser, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err == nil && existingUser != nil {
-		return nil, errors.New("user already exists")
+		return nil, ErrUserAlreadyExists
 	}
 
 	// Hash password
@@ -114,13 +265,67 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 		return nil, err
 	}
 
+	if err := s.assignDefaultRole(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// A failed delivery does not undo the registration; the user can ask
+	// for the token to be resent
+	_ = s.sendEmailVerification(ctx, user)
+
+	// Unverified users only get tokens once they have verified
+	if s.requireEmailVerification {
+		return &AuthResponse{User: user}, nil
+	}
+
 	// Create session and tokens
 	return s.createSessionAndTokens(ctx, user, req.UserAgent, req.IPAddress)
 }
 
 func (s *Service) Login(ctx context.Context, req *LoginRequest) (*AuthResponse, error) {
+	// Refuse locked accounts and addresses before looking at the password
+	lockoutKeys := s.lockoutKeys(req.Email, req.IPAddress)
+	if err := s.checkLockout(ctx, lockoutKeys); err != nil {
+		return nil, err
+	}
+
+	user, err := s.verifyCredentials(ctx, req.Email, req.Password)
+	if err != nil {
+		if err := s.recordLoginFailure(ctx, lockoutKeys); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	// Hashes made with older parameters or imported from bcrypt are replaced
+	// while the password is at hand. Failing to do so does not fail the login;
+	// it is retried next time.
+	_ = s.rehashPassword(ctx, user, req.Password)
+
+	// Only reveal the verification state to someone who knows the password
+	if s.requireEmailVerification && !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	// Users with MFA enabled get a challenge instead of tokens. Their failure
+	// count is only reset once the second factor is verified too.
+	if challenge, required, err := s.mfaChallenge(ctx, user); err != nil || required {
+		return challenge, err
+	}
+
+	if err := s.resetLoginFailures(ctx, lockoutKeys); err != nil {
+		return nil, err
+	}
+
+	// Create session and tokens
+	return s.createSessionAndTokens(ctx, user, req.UserAgent, req.IPAddress)
+}
+
+// verifyCredentials returns the active user with email and password, or
+// ErrInvalidCredentials without saying which check failed
+func (s *Service) verifyCredentials(ctx context.Context, email, password string) (*User, error) {
 	// Get user by email
-	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, ErrInvalidCredentials
 	}
@@ -131,22 +336,130 @@ func (s *Service) Login(ctx context.Context, req *LoginRequest) (*AuthResponse,
 	}
 
 	// Verify password
-	valid, err := s.passwordHasher.VerifyPassword(req.Password, user.PasswordHash)
+	valid, err := s.passwordHasher.VerifyPassword(password, user.PasswordHash)
 	if err != nil || !valid {
 		return nil, ErrInvalidCredentials
 	}
 
-	// Create session and tokens
-	return s.createSessionAndTokens(ctx, user, req.UserAgent, req.IPAddress)
+	return user, nil
+}
+
+// rehashPassword rehashes the user's verified password if the stored hash
+// does not match the hasher's current algorithm and parameters
+func (s *Service) rehashPassword(ctx context.Context, user *User, password string) error {
+	if !s.passwordHasher.NeedsRehash(user.PasswordHash) {
+		return nil
+	}
+
+	hashedPassword, err := s.passwordHasher.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hashedPassword
+	return s.updateUser(ctx, user)
+}
+
+// VerifyEmail redeems a verification token. Tokens sent on registration mark
+// the user's current address as verified; tokens sent by ChangeEmail switch
+// the user to the new address they were sent to. Each token can be redeemed once.
+func (s *Service) VerifyEmail(ctx context.Context, token string) (*User, error) {
+	claims, err := s.jwtManager.ValidateToken(token)
+	if err != nil || (claims.TokenType != EmailVerificationToken && claims.TokenType != EmailChangeToken) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	used, err := s.blacklist.Contains(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	switch {
+	case claims.TokenType == EmailChangeToken && user.Email != claims.Email:
+		now := time.Now()
+		user.Email = claims.Email
+		user.EmailVerifiedAt = &now
+		if err := s.updateUser(ctx, user); err != nil {
+			return nil, err
+		}
+	case user.Email != claims.Email:
+		// The address changed after the token was sent
+		return nil, ErrInvalidVerificationToken
+	case !user.IsEmailVerified():
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := s.updateUser(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.revokeAccessToken(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResendVerification sends a new verification token to an unverified user.
+// Unknown and already verified addresses are silently ignored, and the lookup
+// and delivery run in the background, so neither the response nor its timing
+// reveals which emails are registered.
+func (s *Service) ResendVerification(ctx context.Context, email string) error {
+	s.runInBackground(ctx, "email_verification", func(ctx context.Context) error {
+		user, err := s.userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		if !user.IsActive || user.IsEmailVerified() {
+			return nil
+		}
+
+		return s.sendEmailVerification(ctx, user)
+	})
+
+	return nil
 }
 
 func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
-	// Get session by refresh token
-	session, err := s.sessionRepo.GetByRefreshToken(ctx, refreshToken)
+	// Verify signature and expiry before touching the database
+	claims, err := s.jwtManager.ValidateToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != RefreshToken {
+		return nil, ErrInvalidToken
+	}
+
+	// The session the token was issued for is its token family
+	session, err := s.sessionRepo.GetByID(ctx, claims.SessionID)
 	if err != nil {
 		return nil, ErrSessionNotFound
 	}
 
+	// A correctly signed token that is not the latest one has been rotated
+	// already, so someone is replaying it
+	presentedHash := hashToken(refreshToken)
+	if subtle.ConstantTimeCompare([]byte(presentedHash), []byte(session.RefreshTokenHash)) != 1 {
+		s.revokeReusedFamily(ctx, session)
+		return nil, ErrRefreshTokenReused
+	}
+
 	// Check if session is expired
 	if session.ExpiresAt.Before(time.Now()) {
 		// Delete expired session
@@ -160,71 +473,354 @@ func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*Token
 		return nil, ErrUserNotFound
 	}
 
-	// Generate new token pair
-	tokens, err := s.jwtManager.GenerateTokenPair(user.ID, user.Email, session.ID)
+	// Generate new token pair with the user's current grants
+	grants, err := s.grantsFor(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.jwtManager.GenerateTokenPairWithGrants(user.ID, user.Email, session.ID, grants)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update session with new refresh token
-	session.RefreshToken = tokens.RefreshToken
-	session.UpdatedAt = time.Now()
-	if err := s.sessionRepo.Update(ctx, session); err != nil {
+	// Swap in the new refresh token; losing the race against a concurrent
+	// refresh with the same token also counts as reuse
+	if err := s.sessionRepo.RotateRefreshToken(ctx, session.ID, presentedHash, hashToken(tokens.RefreshToken)); err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			s.revokeReusedFamily(ctx, session)
+		}
 		return nil, err
 	}
 
 	return tokens, nil
 }
 
-func (s *Service) Logout(ctx context.Context, sessionID uuid.UUID) error {
-	return s.sessionRepo.Delete(ctx, sessionID)
+// revokeReusedFamily ends a session whose refresh token was replayed and reports it
+func (s *Service) revokeReusedFamily(ctx context.Context, session *Session) {
+	s.cache.DeleteSession(ctx, session.ID)
+	_ = s.sessionRepo.Delete(ctx, session.ID)
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventRefreshTokenReuse,
+		UserID:     session.UserID,
+		SessionID:  session.ID,
+		IPAddress:  session.IPAddress,
+		UserAgent:  session.UserAgent,
+		OccurredAt: time.Now(),
+	})
+}
+
+// Logout ends the session the access token belongs to and revokes the token
+// itself so it cannot be used for the rest of its lifetime
+func (s *Service) Logout(ctx context.Context, claims *Claims) error {
+	if err := s.revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+
+	s.cache.DeleteSession(ctx, claims.SessionID)
+	return s.sessionRepo.Delete(ctx, claims.SessionID)
+}
+
+// LogoutAllSessions ends every session of the token's user and revokes the
+// presented access token
+func (s *Service) LogoutAllSessions(ctx context.Context, claims *Claims) error {
+	if err := s.revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+
+	s.cache.DeleteUserSessions(ctx, claims.UserID)
+	return s.sessionRepo.DeleteByUserID(ctx, claims.UserID)
+}
+
+// ListSessions returns the active sessions of a user
+func (s *Service) ListSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	return s.sessionRepo.ListByUserID(ctx, userID)
+}
+
+// RevokeSession ends one of the user's sessions. Sessions belonging to other
+// users are reported as not found so their IDs cannot be probed.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	s.cache.DeleteSession(ctx, session.ID)
+	return s.sessionRepo.Delete(ctx, session.ID)
 }
 
-func (s *Service) LogoutAllSessions(ctx context.Context, userID uuid.UUID) error {
+// DeactivateUser disables a user and ends all of their sessions
+func (s *Service) DeactivateUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.IsActive = false
+	if err := s.updateUser(ctx, user); err != nil {
+		return err
+	}
+
+	s.cache.DeleteUserSessions(ctx, userID)
 	return s.sessionRepo.DeleteByUserID(ctx, userID)
 }
 
+// InvalidateUser drops any cached copy of the user and must be called after
+// the user is changed outside of Service
+func (s *Service) InvalidateUser(ctx context.Context, userID uuid.UUID) {
+	s.cache.DeleteUser(ctx, userID)
+}
+
 func (s *Service) ValidateToken(ctx context.Context, tokenString string) (*User, *Session, error) {
+	authn, err := s.Authenticate(ctx, tokenString)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return authn.User, authn.Session, nil
+}
+
+// Authenticate validates an access token and loads its session and user
+func (s *Service) Authenticate(ctx context.Context, tokenString string) (*Authentication, error) {
 	// Validate JWT token
 	claims, err := s.jwtManager.ValidateToken(tokenString)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Ensure it's an access token
 	if claims.TokenType != AccessToken {
-		return nil, nil, ErrInvalidToken
+		return nil, ErrInvalidToken
+	}
+
+	// Reject tokens revoked by logout
+	revoked, err := s.blacklist.Contains(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenBlacklist
 	}
 
 	// Get session to verify it's still active
-	session, err := s.sessionRepo.GetByID(ctx, claims.SessionID)
+	session, err := s.getSession(ctx, claims.SessionID)
 	if err != nil {
-		return nil, nil, ErrSessionNotFound
+		return nil, ErrSessionNotFound
 	}
 
 	// Check if session is expired
 	if session.ExpiresAt.Before(time.Now()) {
 		// Delete expired session
+		s.cache.DeleteSession(ctx, session.ID)
 		_ = s.sessionRepo.Delete(ctx, session.ID)
-		return nil, nil, ErrInvalidSession
+		return nil, ErrInvalidSession
 	}
 
 	// Get user
-	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	user, err := s.getUser(ctx, claims.UserID)
 	if err != nil {
-		return nil, nil, ErrUserNotFound
+		return nil, ErrUserNotFound
 	}
 
 	// Check if user is still active
 	if !user.IsActive {
-		return nil, nil, ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
 	}
 
-	return user, session, nil
+	return &Authentication{
+		User:    user,
+		Session: session,
+		Claims:  claims,
+	}, nil
 }
 
+// CleanupExpiredSessions removes expired sessions, blacklist entries,
+// password reset tokens, login links, social login states, OAuth codes and
+// grants, passkey challenges and stale login failure records
 func (s *Service) CleanupExpiredSessions(ctx context.Context) error {
-	return s.sessionRepo.DeleteExpired(ctx)
+	errs := []error{
+		s.sessionRepo.DeleteExpired(ctx),
+		s.blacklist.DeleteExpired(ctx),
+	}
+	if s.passwordResets != nil {
+		// Used and expired tokens are kept while they count towards the rate limit
+		retention := s.passwordResetPolicy.TTL
+		if s.passwordResetPolicy.RateWindow > retention {
+			retention = s.passwordResetPolicy.RateWindow
+		}
+		errs = append(errs, s.passwordResets.DeleteStale(ctx, time.Now().Add(-retention)))
+	}
+	if s.magicLinks != nil {
+		// Used and expired links are kept while they count towards the rate limit
+		retention := s.magicLinkPolicy.TTL
+		if s.magicLinkPolicy.RateWindow > retention {
+			retention = s.magicLinkPolicy.RateWindow
+		}
+		errs = append(errs, s.magicLinks.DeleteStale(ctx, time.Now().Add(-retention)))
+	}
+	if s.oidcStates != nil {
+		errs = append(errs, s.oidcStates.DeleteExpired(ctx))
+	}
+	if s.oauthCodes != nil {
+		errs = append(errs, s.oauthCodes.DeleteExpired(ctx), s.oauthGrants.DeleteExpired(ctx))
+	}
+	if s.webauthnChallenges != nil {
+		errs = append(errs, s.webauthnChallenges.DeleteExpired(ctx))
+	}
+	if s.loginAttempts != nil {
+		errs = append(errs, s.loginAttempts.DeleteStale(ctx, time.Now().Add(-s.lockout.Window)))
+	}
+	return errors.Join(errs...)
+}
+
+// backgroundTimeout bounds work started by runInBackground
+const backgroundTimeout = time.Minute
+
+// runInBackground runs fn detached from the request, so that callers who
+// must not learn whether an email is registered cannot tell from the
+// response or its latency. Failures are reported as EventNotificationFailed.
+func (s *Service) runInBackground(ctx context.Context, notification string, fn func(ctx context.Context) error) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundTimeout)
+		defer cancel()
+
+		if err := fn(ctx); err != nil {
+			s.events.RecordSecurityEvent(ctx, SecurityEvent{
+				Type:       EventNotificationFailed,
+				Details:    map[string]interface{}{"notification": notification, "error": err.Error()},
+				OccurredAt: time.Now(),
+			})
+		}
+	}()
+}
+
+// WaitForBackground blocks until background deliveries have finished or ctx
+// is done. Call it on shutdown before closing the database.
+func (s *Service) WaitForBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getSession loads a session through the validation cache
+func (s *Service) getSession(ctx context.Context, id uuid.UUID) (*Session, error) {
+	if session, ok := s.cache.GetSession(ctx, id); ok {
+		return session, nil
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.SetSession(ctx, session)
+	return session, nil
+}
+
+// getUser loads a user through the validation cache
+func (s *Service) getUser(ctx context.Context, id uuid.UUID) (*User, error) {
+	if user, ok := s.cache.GetUser(ctx, id); ok {
+		return user, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.SetUser(ctx, user)
+	return user, nil
+}
+
+// updateUser persists user changes and drops the cached copy
+func (s *Service) updateUser(ctx context.Context, user *User) error {
+	defer s.cache.DeleteUser(ctx, user.ID)
+	return s.userRepo.Update(ctx, user)
+}
+
+// claimUnverifiedEmail marks the user's address verified once its owner has
+// proven control of it without a password. Whoever registered the address
+// without verifying it may not be its owner, so their password, sessions and
+// other credentials are dropped rather than handed over with the account.
+func (s *Service) claimUnverifiedEmail(ctx context.Context, user *User) error {
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	user.PasswordHash = ""
+	if err := s.updateUser(ctx, user); err != nil {
+		return err
+	}
+
+	s.cache.DeleteUserSessions(ctx, user.ID)
+	if err := s.sessionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return s.dropUnverifiedCredentials(ctx, user.ID)
+}
+
+// dropUnverifiedCredentials removes the second factor, passkeys and OAuth
+// grants set up on an account before its address was verified. They belong
+// to whoever registered it, who may not be the owner now claiming the address.
+func (s *Service) dropUnverifiedCredentials(ctx context.Context, userID uuid.UUID) error {
+	if s.mfa != nil {
+		if err := s.mfa.Delete(ctx, userID); err != nil && !errors.Is(err, ErrMFANotEnabled) {
+			return err
+		}
+	}
+
+	if s.webauthnCredentials != nil {
+		if err := s.webauthnCredentials.DeleteByUserID(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	if s.oauthGrants != nil {
+		if err := s.oauthGrants.DeleteByUserID(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.oauthConsents.DeleteByUserID(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendEmailVerification issues a verification token for the user's current address
+func (s *Service) sendEmailVerification(ctx context.Context, user *User) error {
+	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, EmailVerificationToken, s.verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.SendEmailVerification(ctx, user, token)
+}
+
+// revokeAccessToken blacklists the token's jti until the token expires.
+// Single-use tokens are spent the same way.
+func (s *Service) revokeAccessToken(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	return s.blacklist.Add(ctx, claims.ID, claims.ExpiresAt.Time)
 }
 
 func (s *Service) createSessionAndTokens(ctx context.Context, user *User, userAgent, ipAddress string) (*AuthResponse, error) {
@@ -240,13 +836,18 @@ func (s *Service) createSessionAndTokens(ctx context.Context, user *User, userAg
 	}
 
 	// Generate tokens
-	tokens, err := s.jwtManager.GenerateTokenPair(user.ID, user.Email, session.ID)
+	grants, err := s.grantsFor(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.jwtManager.GenerateTokenPairWithGrants(user.ID, user.Email, session.ID, grants)
 	if err != nil {
 		return nil, err
 	}
 
-	// Set refresh token in session
-	session.RefreshToken = tokens.RefreshToken
+	// Only a hash of the refresh token is persisted
+	session.RefreshTokenHash = hashToken(tokens.RefreshToken)
 
 	// Save session
 	if err := s.sessionRepo.Create(ctx, session); err != nil {
@@ -257,4 +858,10 @@ func (s *Service) createSessionAndTokens(ctx context.Context, user *User, userAg
 		User:   user,
 		Tokens: tokens,
 	}, nil
-}
\ No newline at end of file
+}
+
+// hashToken returns the hex encoded SHA-256 digest used to store tokens at rest
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
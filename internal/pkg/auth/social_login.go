@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOIDCDisabled          = errors.New("social login is not configured")
+	ErrUnknownOIDCProvider   = errors.New("unknown identity provider")
+	ErrIdentityNotFound      = errors.New("identity not found")
+	ErrIdentityAlreadyLinked = errors.New("identity is already linked to a user")
+	// ErrInvalidOIDCState covers unknown, expired and already used login states
+	ErrInvalidOIDCState = errors.New("invalid or expired login state")
+	// ErrOIDCEmailNotVerified is returned when the provider does not vouch for
+	// the email address, so it cannot be used to find or create a user
+	ErrOIDCEmailNotVerified = errors.New("identity provider has not verified the email address")
+)
+
+// Identity links a user to their account at an external identity provider
+type Identity struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type IdentityRepository interface {
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*Identity, error)
+	// Create links an identity, returning ErrIdentityAlreadyLinked if the
+	// provider account is linked already
+	Create(ctx context.Context, identity *Identity) error
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*Identity, error)
+}
+
+// OIDCState is an outstanding social login. The state sent to the provider
+// and the binding kept by the browser are stored as SHA-256 hashes; the PKCE
+// verifier and nonce never leave the server.
+type OIDCState struct {
+	ID           uuid.UUID `json:"id"`
+	StateHash    string    `json:"-"`
+	BindingHash  string    `json:"-"`
+	Provider     string    `json:"provider"`
+	Nonce        string    `json:"-"`
+	CodeVerifier string    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type OIDCStateRepository interface {
+	Create(ctx context.Context, state *OIDCState) error
+	// Consume deletes an unexpired state with both hashes and returns it, or
+	// returns ErrInvalidOIDCState. A state can be consumed at most once.
+	Consume(ctx context.Context, stateHash, bindingHash string) (*OIDCState, error)
+	DeleteExpired(ctx context.Context) error
+}
+
+// OIDCAuthorization is where to send the user to sign in with a provider.
+// Binding must be stored in the browser that is sent there, in a cookie the
+// provider's redirect carries back, and passed to CompleteOIDCLogin; it
+// stops a callback started by someone else from logging the browser in.
+// SecureBinding is set when the redirect URL is HTTPS, so the cookie can be
+// marked Secure.
+type OIDCAuthorization struct {
+	AuthorizationURL string        `json:"authorization_url"`
+	State            string        `json:"state"`
+	Binding          string        `json:"-"`
+	SecureBinding    bool          `json:"-"`
+	ExpiresIn        time.Duration `json:"-"`
+}
+
+type OIDCCallbackRequest struct {
+	Provider  string `form:"-"`
+	Code      string `form:"code" validate:"required"`
+	State     string `form:"state" validate:"required"`
+	Binding   string `form:"-"`
+	UserAgent string `form:"-"`
+	IPAddress string `form:"-"`
+}
+
+// WithOIDC enables social login through the given providers. Login states
+// stay valid for stateTTL between redirecting to the provider and the callback.
+func WithOIDC(identities IdentityRepository, states OIDCStateRepository, stateTTL time.Duration, providers ...*OIDCProvider) ServiceOption {
+	return func(s *Service) {
+		s.identities = identities
+		s.oidcStates = states
+		s.oidcStateTTL = stateTTL
+		s.oidcProviders = make(map[string]*OIDCProvider, len(providers))
+		for _, provider := range providers {
+			s.oidcProviders[provider.Name()] = provider
+		}
+	}
+}
+
+// BeginOIDCLogin starts a social login, returning the provider URL to send
+// the user to. The state, browser binding, nonce and PKCE verifier are
+// stored for the callback.
+func (s *Service) BeginOIDCLogin(ctx context.Context, providerName string) (*OIDCAuthorization, error) {
+	provider, err := s.oidcProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	binding, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, pkceChallenge(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.oidcStates.Create(ctx, &OIDCState{
+		ID:           uuid.New(),
+		StateHash:    hashToken(state),
+		BindingHash:  hashToken(binding),
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(s.oidcStateTTL),
+		CreatedAt:    now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &OIDCAuthorization{
+		AuthorizationURL: authURL,
+		State:            state,
+		Binding:          binding,
+		SecureBinding:    provider.SecureRedirect(),
+		ExpiresIn:        s.oidcStateTTL,
+	}, nil
+}
+
+// CompleteOIDCLogin handles the provider callback: it redeems the state
+// together with the binding of the browser that began the login, exchanges
+// the code, verifies the ID token and logs the linked user in the same way
+// Login does. Unknown identities are linked to the user with the same
+// verified email, or to a new user if there is none.
+func (s *Service) CompleteOIDCLogin(ctx context.Context, req *OIDCCallbackRequest) (*AuthResponse, error) {
+	provider, err := s.oidcProvider(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Binding == "" {
+		return nil, ErrInvalidOIDCState
+	}
+
+	state, err := s.oidcStates.Consume(ctx, hashToken(req.State), hashToken(req.Binding))
+	if err != nil {
+		return nil, err
+	}
+	if state.Provider != provider.Name() {
+		return nil, ErrInvalidOIDCState
+	}
+
+	tokens, err := provider.Exchange(ctx, req.Code, state.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, state.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveOIDCUser(ctx, provider.Name(), claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrInvalidCredentials
+	}
+
+	// The provider stands in for the password, not for the second factor
+	if challenge, required, err := s.mfaChallenge(ctx, user); err != nil || required {
+		return challenge, err
+	}
+
+	return s.createSessionAndTokens(ctx, user, req.UserAgent, req.IPAddress)
+}
+
+// ListIdentities returns the external identities linked to a user
+func (s *Service) ListIdentities(ctx context.Context, userID uuid.UUID) ([]*Identity, error) {
+	if s.identities == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	return s.identities.ListByUserID(ctx, userID)
+}
+
+// resolveOIDCUser finds the user linked to the provider account, linking or
+// registering one by verified email the first time the account is seen
+func (s *Service) resolveOIDCUser(ctx context.Context, provider string, claims *IDTokenClaims) (*User, error) {
+	identity, err := s.identities.GetByProviderSubject(ctx, provider, claims.Subject)
+	if err == nil {
+		return s.userRepo.GetByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		// The provider proved ownership of the address
+		if !user.IsEmailVerified() {
+			if err := s.claimUnverifiedEmail(ctx, user); err != nil {
+				return nil, err
+			}
+		}
+	case errors.Is(err, ErrUserNotFound):
+		if user, err = s.registerOIDCUser(ctx, claims.Email); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.identities.Create(ctx, &Identity{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Provider:  provider,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// registerOIDCUser creates a verified user without a password. They can set
+// one later through the password reset flow.
+func (s *Service) registerOIDCUser(ctx context.Context, email string) (*User, error) {
+	now := time.Now()
+	user := &User{
+		ID:              uuid.New(),
+		Email:           email,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.assignDefaultRole(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Service) oidcProvider(name string) (*OIDCProvider, error) {
+	if s.identities == nil || s.oidcStates == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	provider, ok := s.oidcProviders[name]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	return provider, nil
+}
+
+// pkceChallenge derives the S256 code challenge for a PKCE verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,549 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// mockOIDCServer is an OpenID provider serving discovery, JWKS and a token
+// endpoint that enforces PKCE. Authorization is simulated by authorize,
+// which records what the relying party put in the authorization URL.
+type mockOIDCServer struct {
+	t      *testing.T
+	server *httptest.Server
+
+	// issuer overrides the issuer in the discovery document when set
+	issuer string
+	// key is published in the JWKS; signer signs ID tokens and is key unless replaced
+	key    *SigningKey
+	signer *SigningKey
+
+	mu    sync.Mutex
+	codes map[string]mockOIDCCode
+}
+
+// mockOIDCCode is an issued authorization code and what it was issued for
+type mockOIDCCode struct {
+	challenge string
+	claims    IDTokenClaims
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	t.Helper()
+
+	key, err := GenerateSigningKey(jwt.SigningMethodES256.Alg())
+	if err != nil {
+		t.Fatalf("failed to generate provider key: %v", err)
+	}
+
+	m := &mockOIDCServer{t: t, key: key, signer: key, codes: make(map[string]mockOIDCCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/jwks", m.jwks)
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockOIDCServer) provider() *OIDCProvider {
+	return NewOIDCProvider(OIDCProviderConfig{
+		Name:         "mock",
+		IssuerURL:    m.server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example.com/callback",
+	}, m.server.Client())
+}
+
+func (m *mockOIDCServer) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := m.server.URL
+	if m.issuer != "" {
+		issuer = m.issuer
+	}
+
+	writeMockJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 issuer,
+		"authorization_endpoint": m.server.URL + "/authorize",
+		"token_endpoint":         m.server.URL + "/token",
+		"jwks_uri":               m.server.URL + "/jwks",
+	})
+}
+
+func (m *mockOIDCServer) jwks(w http.ResponseWriter, r *http.Request) {
+	jwk, _ := m.key.JWK()
+	writeMockJSON(w, http.StatusOK, JSONWebKeySet{Keys: []JSONWebKey{jwk}})
+}
+
+func (m *mockOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	m.mu.Lock()
+	code, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	if !ok || r.PostForm.Get("client_id") != "client" || pkceChallenge(r.PostForm.Get("code_verifier")) != code.challenge {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(m.signer.Method, code.claims)
+	token.Header["kid"] = m.key.ID
+	idToken, err := token.SignedString(m.signer.PrivateKey)
+	if err != nil {
+		m.t.Errorf("failed to sign ID token: %v", err)
+		writeMockJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeMockJSON(w, http.StatusOK, OIDCTokenResponse{AccessToken: "access", TokenType: "Bearer", IDToken: idToken, ExpiresIn: 3600})
+}
+
+// authorize checks the authorization URL and issues a code for an ID token
+// with claims, defaulting the registered claims and the nonce from the URL
+func (m *mockOIDCServer) authorize(authURL string, claims IDTokenClaims) string {
+	m.t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatalf("invalid authorization URL: %v", err)
+	}
+	query := parsed.Query()
+
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		m.t.Fatalf("authorization URL has no S256 PKCE challenge: %s", authURL)
+	}
+	if query.Get("state") == "" || query.Get("nonce") == "" {
+		m.t.Fatalf("authorization URL has no state or nonce: %s", authURL)
+	}
+
+	if claims.Nonce == "" {
+		claims.Nonce = query.Get("nonce")
+	}
+	if claims.Issuer == "" {
+		claims.Issuer = m.server.URL
+	}
+	if claims.Audience == nil {
+		claims.Audience = jwt.ClaimStrings{"client"}
+	}
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	}
+
+	code := uuid.NewString()
+	m.mu.Lock()
+	m.codes[code] = mockOIDCCode{challenge: query.Get("code_challenge"), claims: claims}
+	m.mu.Unlock()
+
+	return code
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	verifiedAt := time.Now().Add(-time.Hour)
+	otherKey, err := GenerateSigningKey(jwt.SigningMethodES256.Alg())
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		// setup adjusts the provider or seeds users before the login starts
+		setup   func(m *mockOIDCServer, users *memoryUserRepository)
+		claims  IDTokenClaims
+		binding func(binding string) string
+		nonce   string
+		wantErr error
+		// check inspects a successful login
+		check func(t *testing.T, result *AuthResponse, users *memoryUserRepository, identities *memoryIdentityRepository)
+	}{
+		{
+			name: "links the existing user with the verified email",
+			setup: func(m *mockOIDCServer, users *memoryUserRepository) {
+				users.add(&User{ID: uuid.New(), Email: "jane@example.com", IsActive: true, EmailVerifiedAt: &verifiedAt})
+			},
+			claims: IDTokenClaims{Email: "jane@example.com", EmailVerified: true, RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}},
+			check: func(t *testing.T, result *AuthResponse, users *memoryUserRepository, identities *memoryIdentityRepository) {
+				existing, _ := users.GetByEmail(context.Background(), "jane@example.com")
+				if result.User.ID != existing.ID {
+					t.Errorf("logged in user %s, want existing user %s", result.User.ID, existing.ID)
+				}
+				identity, err := identities.GetByProviderSubject(context.Background(), "mock", "jane")
+				if err != nil || identity.UserID != existing.ID {
+					t.Errorf("identity not linked to existing user: %v", err)
+				}
+			},
+		},
+		{
+			name:   "registers a new user for an unknown verified email",
+			claims: IDTokenClaims{Email: "new@example.com", EmailVerified: true, RegisteredClaims: jwt.RegisteredClaims{Subject: "new"}},
+			check: func(t *testing.T, result *AuthResponse, users *memoryUserRepository, identities *memoryIdentityRepository) {
+				if result.Tokens == nil || !result.User.IsEmailVerified() {
+					t.Errorf("expected tokens for a verified new user, got %+v", result)
+				}
+			},
+		},
+		{
+			name: "does not link by an unverified email",
+			setup: func(m *mockOIDCServer, users *memoryUserRepository) {
+				users.add(&User{ID: uuid.New(), Email: "jane@example.com", IsActive: true, EmailVerifiedAt: &verifiedAt})
+			},
+			claims:  IDTokenClaims{Email: "jane@example.com", EmailVerified: false, RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}},
+			wantErr: ErrOIDCEmailNotVerified,
+		},
+		{
+			name:    "rejects an ID token with another nonce",
+			claims:  IDTokenClaims{Email: "jane@example.com", EmailVerified: true, RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}},
+			nonce:   "replayed-nonce",
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name: "rejects an ID token signed by a key the provider did not publish",
+			setup: func(m *mockOIDCServer, users *memoryUserRepository) {
+				m.signer = otherKey
+			},
+			claims:  IDTokenClaims{Email: "jane@example.com", EmailVerified: true, RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}},
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name:    "rejects a callback from a browser without the binding",
+			claims:  IDTokenClaims{Email: "jane@example.com", EmailVerified: true, RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}},
+			binding: func(string) string { return "" },
+			wantErr: ErrInvalidOIDCState,
+		},
+		{
+			name:    "rejects a callback with another browser's binding",
+			claims:  IDTokenClaims{Email: "jane@example.com", EmailVerified: true, RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}},
+			binding: func(binding string) string { return binding + "x" },
+			wantErr: ErrInvalidOIDCState,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mock := newMockOIDCServer(t)
+			users := newMemoryUserRepository()
+			identities := &memoryIdentityRepository{}
+			if tt.setup != nil {
+				tt.setup(mock, users)
+			}
+
+			svc := newOIDCTestService(users, identities, mock.provider())
+
+			authorization, err := svc.BeginOIDCLogin(ctx, "mock")
+			if err != nil {
+				t.Fatalf("BeginOIDCLogin() error = %v", err)
+			}
+			if authorization.Binding == "" {
+				t.Fatal("BeginOIDCLogin() returned no browser binding")
+			}
+
+			claims := tt.claims
+			claims.Nonce = tt.nonce
+			code := mock.authorize(authorization.AuthorizationURL, claims)
+
+			binding := authorization.Binding
+			if tt.binding != nil {
+				binding = tt.binding(binding)
+			}
+
+			result, err := svc.CompleteOIDCLogin(ctx, &OIDCCallbackRequest{
+				Provider: "mock",
+				Code:     code,
+				State:    authorization.State,
+				Binding:  binding,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CompleteOIDCLogin() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompleteOIDCLogin() error = %v", err)
+			}
+			tt.check(t, result, users, identities)
+		})
+	}
+}
+
+func TestOIDCLoginStateIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	mock := newMockOIDCServer(t)
+	svc := newOIDCTestService(newMemoryUserRepository(), &memoryIdentityRepository{}, mock.provider())
+
+	authorization, err := svc.BeginOIDCLogin(ctx, "mock")
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin() error = %v", err)
+	}
+
+	claims := IDTokenClaims{Email: "jane@example.com", EmailVerified: true, RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}}
+	req := &OIDCCallbackRequest{
+		Provider: "mock",
+		Code:     mock.authorize(authorization.AuthorizationURL, claims),
+		State:    authorization.State,
+		Binding:  authorization.Binding,
+	}
+	if _, err := svc.CompleteOIDCLogin(ctx, req); err != nil {
+		t.Fatalf("CompleteOIDCLogin() error = %v", err)
+	}
+
+	req.Code = mock.authorize(authorization.AuthorizationURL, claims)
+	if _, err := svc.CompleteOIDCLogin(ctx, req); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("replayed CompleteOIDCLogin() error = %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestOIDCProviderDiscovery(t *testing.T) {
+	t.Run("loads the provider endpoints", func(t *testing.T) {
+		mock := newMockOIDCServer(t)
+
+		authURL, err := mock.provider().AuthCodeURL(context.Background(), "state", "nonce", "challenge")
+		if err != nil {
+			t.Fatalf("AuthCodeURL() error = %v", err)
+		}
+
+		parsed, _ := url.Parse(authURL)
+		if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != mock.server.URL+"/authorize" {
+			t.Errorf("authorization endpoint = %s, want %s", got, mock.server.URL+"/authorize")
+		}
+		if parsed.Query().Get("client_id") != "client" || parsed.Query().Get("redirect_uri") != "https://app.example.com/callback" {
+			t.Errorf("authorization URL missing client parameters: %s", authURL)
+		}
+		if !mock.provider().SecureRedirect() {
+			t.Error("SecureRedirect() = false for an https redirect URL")
+		}
+	})
+
+	t.Run("rejects a document for another issuer", func(t *testing.T) {
+		mock := newMockOIDCServer(t)
+		mock.issuer = "https://attacker.example.com"
+
+		_, err := mock.provider().AuthCodeURL(context.Background(), "state", "nonce", "challenge")
+		if !errors.Is(err, ErrOIDCDiscovery) {
+			t.Fatalf("AuthCodeURL() error = %v, want %v", err, ErrOIDCDiscovery)
+		}
+	})
+}
+
+func TestOIDCProviderExchangeSendsPKCEVerifier(t *testing.T) {
+	ctx := context.Background()
+	mock := newMockOIDCServer(t)
+	provider := mock.provider()
+
+	verifier := "correct-verifier"
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", pkceChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	claims := IDTokenClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}}
+
+	if _, err := provider.Exchange(ctx, mock.authorize(authURL, claims), "wrong-verifier"); !errors.Is(err, ErrOIDCExchange) {
+		t.Fatalf("Exchange() with wrong verifier error = %v, want %v", err, ErrOIDCExchange)
+	}
+
+	tokens, err := provider.Exchange(ctx, mock.authorize(authURL, claims), verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if _, err := provider.VerifyIDToken(ctx, tokens.IDToken, "nonce"); err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+}
+
+func newOIDCTestService(users *memoryUserRepository, identities *memoryIdentityRepository, provider *OIDCProvider) *Service {
+	jwtManager := NewJWTManager("test-secret-that-is-long-enough-for-hs256", time.Minute, time.Hour, "test", "test")
+
+	return NewService(users, &memorySessionRepository{}, jwtManager,
+		WithOIDC(identities, &memoryOIDCStateRepository{}, time.Minute, provider),
+	)
+}
+
+type memoryUserRepository struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]*User
+}
+
+func newMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{users: make(map[uuid.UUID]*User)}
+}
+
+func (r *memoryUserRepository) add(user *User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[user.ID] = user
+}
+
+func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (r *memoryUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *User) error {
+	if _, err := r.GetByEmail(ctx, user.Email); err == nil {
+		return ErrUserAlreadyExists
+	}
+	copied := *user
+	r.add(&copied)
+	return nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, user *User) error {
+	copied := *user
+	r.add(&copied)
+	return nil
+}
+
+// memorySessionRepository keeps sessions only as far as the login flows need
+type memorySessionRepository struct {
+	mu       sync.Mutex
+	sessions []*Session
+}
+
+func (r *memorySessionRepository) Create(ctx context.Context, session *Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions = append(r.sessions, session)
+	return nil
+}
+
+func (r *memorySessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.ID == id {
+			return session, nil
+		}
+	}
+	return nil, ErrSessionNotFound
+}
+
+func (r *memorySessionRepository) GetByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (*Session, error) {
+	return nil, ErrSessionNotFound
+}
+
+func (r *memorySessionRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	return nil, nil
+}
+
+func (r *memorySessionRepository) Update(ctx context.Context, session *Session) error { return nil }
+
+func (r *memorySessionRepository) RotateRefreshToken(ctx context.Context, id uuid.UUID, previousHash, nextHash string) error {
+	return ErrRefreshTokenReused
+}
+
+func (r *memorySessionRepository) Delete(ctx context.Context, id uuid.UUID) error { return nil }
+
+func (r *memorySessionRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (r *memorySessionRepository) DeleteByUserIDExcept(ctx context.Context, userID, keepID uuid.UUID) error {
+	return nil
+}
+
+func (r *memorySessionRepository) DeleteExpired(ctx context.Context) error { return nil }
+
+type memoryIdentityRepository struct {
+	mu         sync.Mutex
+	identities []*Identity
+}
+
+func (r *memoryIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, ErrIdentityNotFound
+}
+
+func (r *memoryIdentityRepository) Create(ctx context.Context, identity *Identity) error {
+	if _, err := r.GetByProviderSubject(ctx, identity.Provider, identity.Subject); err == nil {
+		return ErrIdentityAlreadyLinked
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *memoryIdentityRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var identities []*Identity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+type memoryOIDCStateRepository struct {
+	mu     sync.Mutex
+	states []*OIDCState
+}
+
+func (r *memoryOIDCStateRepository) Create(ctx context.Context, state *OIDCState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, state)
+	return nil
+}
+
+func (r *memoryOIDCStateRepository) Consume(ctx context.Context, stateHash, bindingHash string) (*OIDCState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, state := range r.states {
+		if state.StateHash == stateHash && state.BindingHash == bindingHash && state.ExpiresAt.After(time.Now()) {
+			r.states = append(r.states[:i], r.states[i+1:]...)
+			return state, nil
+		}
+	}
+	return nil, ErrInvalidOIDCState
+}
+
+func (r *memoryOIDCStateRepository) DeleteExpired(ctx context.Context) error { return nil }
@@ -0,0 +1,658 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWebAuthnDisabled = errors.New("passkey login is not configured")
+	// ErrInvalidWebAuthnChallenge covers unknown, expired and already used challenges
+	ErrInvalidWebAuthnChallenge = errors.New("invalid or expired WebAuthn challenge")
+	// ErrInvalidWebAuthnResponse covers authenticator responses that are
+	// malformed or do not match the challenge, origin or relying party
+	ErrInvalidWebAuthnResponse        = errors.New("invalid WebAuthn response")
+	ErrWebAuthnCredentialNotFound     = errors.New("passkey not found")
+	ErrWebAuthnCredentialExists       = errors.New("passkey is already registered")
+	ErrUnsupportedWebAuthnAttestation = errors.New("only \"none\" attestation is supported")
+	// ErrWebAuthnSignCountStale means an assertion's signature counter did not
+	// move past the stored one, so the passkey has likely been cloned
+	ErrWebAuthnSignCountStale = errors.New("passkey signature counter did not increase")
+)
+
+// Ceremony names, as they appear in the type member of the client data
+const (
+	WebAuthnCeremonyRegistration   = "webauthn.create"
+	WebAuthnCeremonyAuthentication = "webauthn.get"
+)
+
+// Authenticator data flags (WebAuthn section 6.1)
+const (
+	authDataUserPresent        byte = 0x01
+	authDataUserVerified       byte = 0x04
+	authDataAttestedCredential byte = 0x40
+	authDataExtensions         byte = 0x80
+)
+
+// webauthnEncoding is how WebAuthn JSON carries binary values
+var webauthnEncoding = base64.RawURLEncoding
+
+// WebAuthnConfig describes the relying party passkeys are scoped to
+type WebAuthnConfig struct {
+	// RPID is the domain credentials are bound to, e.g. example.com
+	RPID   string
+	RPName string
+	// Origins are the exact origins ceremonies may run on, e.g. https://example.com
+	Origins      []string
+	ChallengeTTL time.Duration
+}
+
+// WebAuthnCredential is a registered passkey. PublicKey holds the COSE_Key
+// the authenticator produced at registration.
+type WebAuthnCredential struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	CredentialID []byte     `json:"-"`
+	PublicKey    []byte     `json:"-"`
+	SignCount    uint32     `json:"-"`
+	Name         string     `json:"name"`
+	Transports   []string   `json:"transports"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type WebAuthnCredentialRepository interface {
+	// Create stores a credential, returning ErrWebAuthnCredentialExists if
+	// its credential ID is already registered
+	Create(ctx context.Context, credential *WebAuthnCredential) error
+	GetByCredentialID(ctx context.Context, credentialID []byte) (*WebAuthnCredential, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*WebAuthnCredential, error)
+	// UpdateSignCount records a successful assertion. The stored counter is
+	// only replaced by a greater one, or by zero while it is zero for
+	// authenticators that do not count; otherwise ErrWebAuthnSignCountStale
+	// is returned. Checking and writing in one statement stops two clones
+	// from both getting through with the same counter.
+	UpdateSignCount(ctx context.Context, id uuid.UUID, signCount uint32, usedAt time.Time) error
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+}
+
+// WebAuthnChallenge is an outstanding ceremony. UserID is nil for
+// authentication, where the user is only known once a passkey is presented.
+type WebAuthnChallenge struct {
+	ID            uuid.UUID  `json:"id"`
+	ChallengeHash string     `json:"-"`
+	UserID        *uuid.UUID `json:"user_id,omitempty"`
+	Ceremony      string     `json:"ceremony"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type WebAuthnChallengeRepository interface {
+	Create(ctx context.Context, challenge *WebAuthnChallenge) error
+	// Consume deletes and returns the unexpired challenge for the ceremony,
+	// or returns ErrInvalidWebAuthnChallenge. A challenge can be consumed at
+	// most once.
+	Consume(ctx context.Context, challengeHash, ceremony string) (*WebAuthnChallenge, error)
+	DeleteExpired(ctx context.Context) error
+}
+
+// WebAuthnRelyingParty, WebAuthnUserEntity, WebAuthnCredentialParameter,
+// WebAuthnCredentialDescriptor and WebAuthnAuthenticatorSelection mirror the
+// WebAuthn dictionaries of the same names, with binary members base64url
+// encoded.
+
+type WebAuthnRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type WebAuthnUserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type WebAuthnCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type WebAuthnCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// WebAuthnRegistrationOptions is passed to navigator.credentials.create as
+// the publicKey member, after decoding challenge and user.id
+type WebAuthnRegistrationOptions struct {
+	Challenge              string                         `json:"challenge"`
+	RP                     WebAuthnRelyingParty           `json:"rp"`
+	User                   WebAuthnUserEntity             `json:"user"`
+	PubKeyCredParams       []WebAuthnCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	Attestation            string                         `json:"attestation"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+}
+
+// WebAuthnLoginOptions is passed to navigator.credentials.get as the
+// publicKey member, after decoding challenge. No credentials are listed, so
+// the browser offers the passkeys it holds for the relying party.
+type WebAuthnLoginOptions struct {
+	Challenge        string                         `json:"challenge"`
+	Timeout          int64                          `json:"timeout"`
+	RPID             string                         `json:"rpId"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+// WebAuthnAttestationResponse is the AuthenticatorAttestationResponse of a
+// new credential, with binary members base64url encoded
+type WebAuthnAttestationResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON" validate:"required"`
+	AttestationObject string   `json:"attestationObject" validate:"required"`
+	Transports        []string `json:"transports"`
+}
+
+type WebAuthnRegistrationCredential struct {
+	ID       string                      `json:"id" validate:"required"`
+	RawID    string                      `json:"rawId" validate:"required"`
+	Type     string                      `json:"type" validate:"required,eq=public-key"`
+	Response WebAuthnAttestationResponse `json:"response"`
+}
+
+type FinishWebAuthnRegistrationRequest struct {
+	// Name labels the passkey in the user's list, e.g. "Work laptop"
+	Name string `json:"name" validate:"max=100"`
+	// CurrentPassword is required unless the account has no password
+	CurrentPassword string `json:"current_password"`
+	// MFACode is a TOTP or recovery code, required when the user has MFA enabled
+	MFACode    string                         `json:"mfa_code"`
+	Credential WebAuthnRegistrationCredential `json:"credential"`
+}
+
+// WebAuthnAssertionResponse is the AuthenticatorAssertionResponse of a login,
+// with binary members base64url encoded
+type WebAuthnAssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" validate:"required"`
+	AuthenticatorData string `json:"authenticatorData" validate:"required"`
+	Signature         string `json:"signature" validate:"required"`
+	UserHandle        string `json:"userHandle"`
+}
+
+type WebAuthnAssertionCredential struct {
+	ID       string                    `json:"id" validate:"required"`
+	RawID    string                    `json:"rawId" validate:"required"`
+	Type     string                    `json:"type" validate:"required,eq=public-key"`
+	Response WebAuthnAssertionResponse `json:"response"`
+}
+
+type FinishWebAuthnLoginRequest struct {
+	Credential WebAuthnAssertionCredential `json:"credential"`
+	UserAgent  string                      `json:"-"`
+	IPAddress  string                      `json:"-"`
+}
+
+// WithWebAuthn enables passkey registration and login
+func WithWebAuthn(credentials WebAuthnCredentialRepository, challenges WebAuthnChallengeRepository, config WebAuthnConfig) ServiceOption {
+	return func(s *Service) {
+		s.webauthnCredentials = credentials
+		s.webauthnChallenges = challenges
+		s.webauthnConfig = config
+	}
+}
+
+// BeginWebAuthnRegistration starts adding a passkey to the token's user.
+// Users whose address is unverified are refused: whoever registered it may
+// not own it, and a passkey would let them back in once the owner claims it.
+func (s *Service) BeginWebAuthnRegistration(ctx context.Context, claims *Claims) (*WebAuthnRegistrationOptions, error) {
+	if s.webauthnCredentials == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	existing, err := s.webauthnCredentials.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticators refuse to create a second passkey for the same account
+	exclude := make([]WebAuthnCredentialDescriptor, 0, len(existing))
+	for _, credential := range existing {
+		exclude = append(exclude, WebAuthnCredentialDescriptor{
+			Type:       "public-key",
+			ID:         webauthnEncoding.EncodeToString(credential.CredentialID),
+			Transports: credential.Transports,
+		})
+	}
+
+	challenge, err := s.newWebAuthnChallenge(ctx, &user.ID, WebAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnRegistrationOptions{
+		Challenge: challenge,
+		RP: WebAuthnRelyingParty{
+			ID:   s.webauthnConfig.RPID,
+			Name: s.webauthnConfig.RPName,
+		},
+		User: WebAuthnUserEntity{
+			// The user handle is the opaque user ID, never the email
+			ID:          webauthnEncoding.EncodeToString(user.ID[:]),
+			Name:        user.Email,
+			DisplayName: user.Email,
+		},
+		PubKeyCredParams: []WebAuthnCredentialParameter{
+			{Type: "public-key", Alg: coseAlgES256},
+			{Type: "public-key", Alg: coseAlgEdDSA},
+			{Type: "public-key", Alg: coseAlgRS256},
+		},
+		Timeout:            s.webauthnConfig.ChallengeTTL.Milliseconds(),
+		Attestation:        "none",
+		ExcludeCredentials: exclude,
+		// Login is usernameless, so the passkey must be discoverable
+		AuthenticatorSelection: WebAuthnAuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "preferred",
+		},
+	}, nil
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's attestation for a
+// challenge issued to the token's user and stores the new passkey. A passkey
+// can log in on its own, so the user must reauthenticate rather than rely on
+// the access token alone. Only "none" attestation is accepted; the
+// authenticator's make and model are not verified.
+func (s *Service) FinishWebAuthnRegistration(ctx context.Context, claims *Claims, req *FinishWebAuthnRegistrationRequest) (*WebAuthnCredential, error) {
+	if s.webauthnCredentials == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	if _, err := s.reauthenticate(ctx, claims, req.CurrentPassword, req.MFACode); err != nil {
+		return nil, err
+	}
+
+	response := req.Credential.Response
+	challenge, _, err := s.verifyWebAuthnClientData(ctx, response.ClientDataJSON, WebAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID == nil || *challenge.UserID != claims.UserID {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	attestationObject, err := webauthnEncoding.DecodeString(strings.TrimRight(response.AttestationObject, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: attestation object is not base64url", ErrInvalidWebAuthnResponse)
+	}
+
+	decoded, n, err := decodeCBOR(attestationObject)
+	if err != nil || n != len(attestationObject) {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrInvalidWebAuthnResponse)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrInvalidWebAuthnResponse)
+	}
+
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[interface{}]interface{})
+	if format != "none" || len(statement) != 0 {
+		return nil, ErrUnsupportedWebAuthnAttestation
+	}
+
+	rawAuthData, _ := attestation["authData"].([]byte)
+	authData, err := s.parseWebAuthnAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&authDataAttestedCredential == 0 {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalidWebAuthnResponse)
+	}
+
+	rawID, err := webauthnEncoding.DecodeString(strings.TrimRight(req.Credential.RawID, "="))
+	if err != nil || !bytes.Equal(rawID, authData.credentialID) {
+		return nil, fmt.Errorf("%w: credential ID does not match authenticator data", ErrInvalidWebAuthnResponse)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	now := time.Now()
+	credential := &WebAuthnCredential{
+		ID:           uuid.New(),
+		UserID:       claims.UserID,
+		CredentialID: authData.credentialID,
+		PublicKey:    authData.publicKey,
+		SignCount:    authData.signCount,
+		Name:         name,
+		Transports:   response.Transports,
+		CreatedAt:    now,
+	}
+	if err := s.webauthnCredentials.Create(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventPasskeyAdded,
+		UserID:     claims.UserID,
+		SessionID:  claims.SessionID,
+		Details:    map[string]interface{}{"passkey_id": credential.ID},
+		OccurredAt: now,
+	})
+
+	return credential, nil
+}
+
+// BeginWebAuthnLogin issues a challenge for a usernameless passkey login
+func (s *Service) BeginWebAuthnLogin(ctx context.Context) (*WebAuthnLoginOptions, error) {
+	if s.webauthnCredentials == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	challenge, err := s.newWebAuthnChallenge(ctx, nil, WebAuthnCeremonyAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnLoginOptions{
+		Challenge:        challenge,
+		Timeout:          s.webauthnConfig.ChallengeTTL.Milliseconds(),
+		RPID:             s.webauthnConfig.RPID,
+		AllowCredentials: []WebAuthnCredentialDescriptor{},
+		UserVerification: "preferred",
+	}, nil
+}
+
+// FinishWebAuthnLogin verifies a passkey assertion and logs its owner in the
+// same way Login does. An assertion made with user verification (PIN or
+// biometrics) already counts as two factors; without it, users with MFA
+// enabled get a challenge instead of tokens.
+func (s *Service) FinishWebAuthnLogin(ctx context.Context, req *FinishWebAuthnLoginRequest) (*AuthResponse, error) {
+	if s.webauthnCredentials == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	response := req.Credential.Response
+	_, clientDataJSON, err := s.verifyWebAuthnClientData(ctx, response.ClientDataJSON, WebAuthnCeremonyAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	rawID, err := webauthnEncoding.DecodeString(strings.TrimRight(req.Credential.RawID, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: credential ID is not base64url", ErrInvalidWebAuthnResponse)
+	}
+	rawAuthData, err := webauthnEncoding.DecodeString(strings.TrimRight(response.AuthenticatorData, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: authenticator data is not base64url", ErrInvalidWebAuthnResponse)
+	}
+	signature, err := webauthnEncoding.DecodeString(strings.TrimRight(response.Signature, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not base64url", ErrInvalidWebAuthnResponse)
+	}
+
+	credential, err := s.webauthnCredentials.GetByCredentialID(ctx, rawID)
+	if err != nil {
+		if errors.Is(err, ErrWebAuthnCredentialNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	// A discoverable credential reports the user it was created for
+	if response.UserHandle != "" {
+		userHandle, err := webauthnEncoding.DecodeString(strings.TrimRight(response.UserHandle, "="))
+		if err != nil || subtle.ConstantTimeCompare(userHandle, credential.UserID[:]) != 1 {
+			return nil, ErrInvalidCredentials
+		}
+	}
+
+	authData, err := s.parseWebAuthnAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, _, err := parseCOSEKey(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	// The authenticator signs its data followed by the client data hash
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := make([]byte, 0, len(rawAuthData)+len(clientDataHash))
+	signed = append(append(signed, rawAuthData...), clientDataHash[:]...)
+	if !publicKey.verify(signed, signature) {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Counters only ever increase, so a stale one means the private key
+	// exists in two places. Authenticators that do not count report zero.
+	err = s.webauthnCredentials.UpdateSignCount(ctx, credential.ID, authData.signCount, time.Now())
+	if errors.Is(err, ErrWebAuthnSignCountStale) {
+		s.events.RecordSecurityEvent(ctx, SecurityEvent{
+			Type:      EventPasskeyCloned,
+			UserID:    credential.UserID,
+			IPAddress: req.IPAddress,
+			UserAgent: req.UserAgent,
+			Details: map[string]interface{}{
+				"passkey_id":      credential.ID,
+				"stored_count":    credential.SignCount,
+				"presented_count": authData.signCount,
+			},
+			OccurredAt: time.Now(),
+		})
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, credential.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrInvalidCredentials
+	}
+
+	if authData.flags&authDataUserVerified == 0 {
+		if challenge, required, err := s.mfaChallenge(ctx, user); err != nil || required {
+			return challenge, err
+		}
+	}
+
+	return s.createSessionAndTokens(ctx, user, req.UserAgent, req.IPAddress)
+}
+
+// ListWebAuthnCredentials returns the user's passkeys
+func (s *Service) ListWebAuthnCredentials(ctx context.Context, userID uuid.UUID) ([]*WebAuthnCredential, error) {
+	if s.webauthnCredentials == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	return s.webauthnCredentials.ListByUserID(ctx, userID)
+}
+
+// DeleteWebAuthnCredential removes one of the token's user's passkeys
+func (s *Service) DeleteWebAuthnCredential(ctx context.Context, claims *Claims, id uuid.UUID) error {
+	if s.webauthnCredentials == nil {
+		return ErrWebAuthnDisabled
+	}
+
+	if err := s.webauthnCredentials.Delete(ctx, claims.UserID, id); err != nil {
+		return err
+	}
+
+	s.events.RecordSecurityEvent(ctx, SecurityEvent{
+		Type:       EventPasskeyRemoved,
+		UserID:     claims.UserID,
+		SessionID:  claims.SessionID,
+		Details:    map[string]interface{}{"passkey_id": id},
+		OccurredAt: time.Now(),
+	})
+
+	return nil
+}
+
+// newWebAuthnChallenge stores a hash of a fresh random challenge and returns
+// the challenge base64url encoded
+func (s *Service) newWebAuthnChallenge(ctx context.Context, userID *uuid.UUID, ceremony string) (string, error) {
+	challenge, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := s.webauthnChallenges.Create(ctx, &WebAuthnChallenge{
+		ID:            uuid.New(),
+		ChallengeHash: hashToken(challenge),
+		UserID:        userID,
+		Ceremony:      ceremony,
+		ExpiresAt:     now.Add(s.webauthnConfig.ChallengeTTL),
+		CreatedAt:     now,
+	}); err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// collectedClientData is the part of the client data the relying party checks
+type collectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// verifyWebAuthnClientData checks the client data of a ceremony and consumes
+// its challenge, returning the challenge and the raw client data JSON that
+// assertion signatures cover
+func (s *Service) verifyWebAuthnClientData(ctx context.Context, encoded, ceremony string) (*WebAuthnChallenge, []byte, error) {
+	raw, err := webauthnEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: client data is not base64url", ErrInvalidWebAuthnResponse)
+	}
+
+	var clientData collectedClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed client data", ErrInvalidWebAuthnResponse)
+	}
+
+	if clientData.Type != ceremony {
+		return nil, nil, fmt.Errorf("%w: unexpected ceremony %q", ErrInvalidWebAuthnResponse, clientData.Type)
+	}
+	if clientData.CrossOrigin || !containsString(s.webauthnConfig.Origins, clientData.Origin) {
+		return nil, nil, fmt.Errorf("%w: origin %q is not allowed", ErrInvalidWebAuthnResponse, clientData.Origin)
+	}
+
+	challenge, err := s.webauthnChallenges.Consume(ctx, hashToken(clientData.Challenge), ceremony)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return challenge, raw, nil
+}
+
+// webauthnAuthenticatorData is the decoded authenticator data (WebAuthn
+// section 6.1). The credential fields are only set during registration.
+type webauthnAuthenticatorData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseWebAuthnAuthenticatorData decodes authenticator data and checks that
+// it is scoped to this relying party and that the user was present
+func (s *Service) parseWebAuthnAuthenticatorData(data []byte) (*webauthnAuthenticatorData, error) {
+	// RP ID hash, flags and sign count come first
+	const headerLen = 32 + 1 + 4
+	if len(data) < headerLen {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrInvalidWebAuthnResponse)
+	}
+
+	rpIDHash := sha256.Sum256([]byte(s.webauthnConfig.RPID))
+	if subtle.ConstantTimeCompare(data[:32], rpIDHash[:]) != 1 {
+		return nil, fmt.Errorf("%w: credential belongs to another relying party", ErrInvalidWebAuthnResponse)
+	}
+
+	authData := &webauthnAuthenticatorData{
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if authData.flags&authDataUserPresent == 0 {
+		return nil, fmt.Errorf("%w: user was not present", ErrInvalidWebAuthnResponse)
+	}
+
+	rest := data[headerLen:]
+	if authData.flags&authDataAttestedCredential != 0 {
+		// AAGUID, then the length-prefixed credential ID, then the COSE key
+		if len(rest) < 16+2 {
+			return nil, fmt.Errorf("%w: attested credential data too short", ErrInvalidWebAuthnResponse)
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+			return nil, fmt.Errorf("%w: invalid credential ID length", ErrInvalidWebAuthnResponse)
+		}
+		authData.credentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		_, keyLen, err := parseCOSEKey(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err)
+		}
+		authData.publicKey = rest[:keyLen]
+		rest = rest[keyLen:]
+	}
+
+	if authData.flags&authDataExtensions != 0 {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed extensions", ErrInvalidWebAuthnResponse)
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing bytes in authenticator data", ErrInvalidWebAuthnResponse)
+	}
+
+	return authData, nil
+}
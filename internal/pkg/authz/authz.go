@@ -0,0 +1,98 @@
+// Package authz decides whether an authenticated user may perform an action
+// on a particular resource. Role checks that do not depend on the resource
+// belong in auth.Middleware; this package covers ownership and other
+// per-record rules.
+package authz
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	apperrors "github.com/yantology/golang_template/pkg/errors"
+)
+
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("not allowed to perform this action")
+)
+
+// Subject is the user an authorization decision is made for
+type Subject struct {
+	ID    uuid.UUID
+	Roles []string
+}
+
+// HasRole reports whether the subject holds role
+func (s Subject) HasRole(role string) bool {
+	for _, r := range s.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Resource is the record being acted on. OwnerID is uuid.Nil for records
+// that have no owner.
+type Resource struct {
+	Type    string
+	ID      string
+	OwnerID uuid.UUID
+}
+
+// Policy decides whether subject may perform action on resource
+type Policy interface {
+	Can(ctx context.Context, subject Subject, action string, resource Resource) (bool, error)
+}
+
+// Authorizer applies a Policy to the user authenticated on a request
+type Authorizer struct {
+	policy Policy
+}
+
+func NewAuthorizer(policy Policy) *Authorizer {
+	return &Authorizer{policy: policy}
+}
+
+// Authorize checks whether the user authenticated on ctx may perform action
+// on resource. ctx must be the *gin.Context of a route behind RequireAuth
+// itself: the middleware stores the user on the gin context, so
+// c.Request.Context() carries no subject and is always refused with
+// ErrUnauthenticated. Refusals are returned as *apperrors.AppError wrapping
+// ErrUnauthenticated or ErrForbidden, so handlers can pass them straight to
+// their error responses.
+func (a *Authorizer) Authorize(ctx context.Context, action string, resource Resource) error {
+	subject, ok := SubjectFromContext(ctx)
+	if !ok {
+		return apperrors.Wrap(ErrUnauthenticated, apperrors.ErrorCodeUnauthorized, ErrUnauthenticated.Error())
+	}
+
+	allowed, err := a.policy.Can(ctx, subject, action, resource)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return apperrors.Wrap(ErrForbidden, apperrors.ErrorCodeForbidden, ErrForbidden.Error())
+	}
+
+	return nil
+}
+
+// SubjectFromContext builds the subject from the user set by auth.Middleware,
+// taking roles from the access token claims when they are present
+func SubjectFromContext(ctx context.Context) (Subject, bool) {
+	user, ok := auth.GetUserFromStdContext(ctx)
+	if !ok || user == nil {
+		return Subject{}, false
+	}
+
+	subject := Subject{ID: user.ID}
+	if claims, ok := auth.GetClaimsFromStdContext(ctx); ok && claims != nil {
+		subject.Roles = claims.Roles
+	}
+
+	return subject, true
+}
@@ -0,0 +1,158 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+)
+
+// Wildcard matches any resource type, action or role in a rule
+const Wildcard = "*"
+
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+)
+
+// Rule grants or refuses actions on a resource type. A rule applies when the
+// resource type and action match and every condition holds: the subject has
+// one of Roles (if any are listed) and owns the resource (if Owner is set).
+type Rule struct {
+	Resource string   `yaml:"resource"`
+	Actions  []string `yaml:"actions"`
+	Roles    []string `yaml:"roles,omitempty"`
+	Owner    bool     `yaml:"owner,omitempty"`
+	Effect   Effect   `yaml:"effect"`
+}
+
+// RuleFile is the YAML document a RulePolicy is loaded from
+type RuleFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// RulePolicy is a Policy evaluated from a list of rules. Anything no rule
+// allows is refused, and a matching deny rule wins over any allow rule.
+type RulePolicy struct {
+	rules []Rule
+}
+
+// DefaultRules let admins do anything and users do anything to resources they own
+func DefaultRules() []Rule {
+	return []Rule{
+		{Resource: Wildcard, Actions: []string{Wildcard}, Roles: []string{auth.RoleAdmin}, Effect: Allow},
+		{Resource: Wildcard, Actions: []string{Wildcard}, Owner: true, Effect: Allow},
+	}
+}
+
+// NewRulePolicy creates a policy from rules after validating them
+func NewRulePolicy(rules []Rule) (*RulePolicy, error) {
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+
+	return &RulePolicy{rules: rules}, nil
+}
+
+// ParseRules creates a policy from a YAML rule document
+func ParseRules(data []byte) (*RulePolicy, error) {
+	var file RuleFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse authorization rules: %w", err)
+	}
+
+	return NewRulePolicy(file.Rules)
+}
+
+// LoadRuleFile creates a policy from the YAML rule document at path
+func LoadRuleFile(path string) (*RulePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorization rules: %w", err)
+	}
+
+	return ParseRules(data)
+}
+
+func (p *RulePolicy) Can(ctx context.Context, subject Subject, action string, resource Resource) (bool, error) {
+	allowed := false
+
+	for _, rule := range p.rules {
+		if !rule.matches(subject, action, resource) {
+			continue
+		}
+		if rule.Effect == Deny {
+			return false, nil
+		}
+		allowed = true
+	}
+
+	return allowed, nil
+}
+
+// matches reports whether the rule applies to subject performing action on resource
+func (r Rule) matches(subject Subject, action string, resource Resource) bool {
+	if r.Resource != Wildcard && r.Resource != resource.Type {
+		return false
+	}
+
+	if !containsOrWildcard(r.Actions, action) {
+		return false
+	}
+
+	if len(r.Roles) > 0 && !r.hasAnyRole(subject) {
+		return false
+	}
+
+	if r.Owner && (resource.OwnerID == uuid.Nil || resource.OwnerID != subject.ID) {
+		return false
+	}
+
+	return true
+}
+
+func (r Rule) hasAnyRole(subject Subject) bool {
+	for _, role := range r.Roles {
+		if role == Wildcard || subject.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOrWildcard(values []string, value string) bool {
+	for _, v := range values {
+		if v == Wildcard || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRules reports every malformed rule at once
+func validateRules(rules []Rule) error {
+	var errs []error
+
+	for i, rule := range rules {
+		if rule.Resource == "" {
+			errs = append(errs, fmt.Errorf("rule %d: resource is required", i))
+		}
+
+		if len(rule.Actions) == 0 {
+			errs = append(errs, fmt.Errorf("rule %d: at least one action is required", i))
+		}
+
+		if rule.Effect != Allow && rule.Effect != Deny {
+			errs = append(errs, fmt.Errorf("rule %d: effect must be %q or %q", i, Allow, Deny))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// AdvisoryLocker provides cluster-wide named locks using PostgreSQL session
+// level advisory locks, so only one replica holds a given name at a time
+type AdvisoryLocker struct {
+	db *sql.DB
+}
+
+// NewAdvisoryLocker creates an AdvisoryLocker on top of db
+func NewAdvisoryLocker(db *sql.DB) *AdvisoryLocker {
+	return &AdvisoryLocker{db: db}
+}
+
+// TryLock attempts to take the lock for name without waiting. Advisory locks
+// belong to a database session, so a connection is held until unlock is called.
+func (l *AdvisoryLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, name).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// Use a fresh context: the caller's may already be cancelled
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// Closing the connection would also release the lock, but it may be
+		// returned to the pool, so unlock explicitly
+		_, _ = conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, name)
+		conn.Close()
+	}
+
+	return unlock, true, nil
+}
@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/yantology/golang_template/internal/pkg/auth"
+)
+
+// AuthNotifier delivers auth.Service tokens as links in emails
+type AuthNotifier struct {
+	mailer   Mailer
+	renderer *Renderer
+	appName  string
+	baseURL  string
+}
+
+// NewAuthNotifier creates an auth.Notifier. Links point at baseURL, which is
+// normally the frontend that calls the API with the token.
+func NewAuthNotifier(m Mailer, renderer *Renderer, appName, baseURL string) *AuthNotifier {
+	return &AuthNotifier{
+		mailer:   m,
+		renderer: renderer,
+		appName:  appName,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// linkData is passed to every auth email template
+type linkData struct {
+	AppName string
+	Email   string
+	Link    string
+}
+
+func (n *AuthNotifier) SendEmailVerification(ctx context.Context, user *auth.User, token string) error {
+	return n.sendLink(ctx, "email_verification", user.Email, "/verify-email", token)
+}
+
+func (n *AuthNotifier) SendPasswordReset(ctx context.Context, user *auth.User, token string) error {
+	return n.sendLink(ctx, "password_reset", user.Email, "/reset-password", token)
+}
+
+func (n *AuthNotifier) SendEmailChangeVerification(ctx context.Context, user *auth.User, newEmail, token string) error {
+	return n.sendLink(ctx, "email_change", newEmail, "/verify-email", token)
+}
+
+func (n *AuthNotifier) SendMagicLink(ctx context.Context, user *auth.User, token string) error {
+	return n.sendLink(ctx, "magic_link", user.Email, "/magic-link", token)
+}
+
+func (n *AuthNotifier) sendLink(ctx context.Context, template, email, path, token string) error {
+	link := n.baseURL + path + "?" + url.Values{"token": {token}}.Encode()
+
+	msg, err := n.renderer.Render(template, linkData{
+		AppName: n.appName,
+		Email:   email,
+		Link:    link,
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = []string{email}
+
+	return n.mailer.Send(ctx, msg)
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/yantology/golang_template/internal/pkg/logger"
+)
+
+// LogMailer writes messages to the application log instead of sending them.
+// Message bodies often contain one-time tokens, so it is meant for
+// development only.
+type LogMailer struct {
+	logger logger.Logger
+	from   string
+}
+
+// NewLogMailer creates a LogMailer that attributes messages to from
+func NewLogMailer(log logger.Logger, from string) *LogMailer {
+	return &LogMailer{logger: log, from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	from, to, err := envelope(msg, m.from)
+	if err != nil {
+		return err
+	}
+
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.Address
+	}
+
+	m.logger.WithFields(map[string]interface{}{
+		"from":    from.Address,
+		"to":      strings.Join(recipients, ", "),
+		"subject": msg.Subject,
+		"body":    msg.Text,
+	}).Info("Email sent to log transport")
+
+	return nil
+}
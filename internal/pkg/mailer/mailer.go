@@ -0,0 +1,76 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+
+	"github.com/yantology/golang_template/internal/config"
+	"github.com/yantology/golang_template/internal/pkg/logger"
+)
+
+// Message is an email with a plain text body and an optional HTML alternative
+type Message struct {
+	// From defaults to the sender configured on the Mailer
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages through a transport
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New creates the Mailer for the transport selected in cfg
+func New(cfg config.MailConfig, log logger.Logger) (Mailer, error) {
+	from := (&mail.Address{Name: cfg.FromName, Address: cfg.From}).String()
+
+	switch cfg.Transport {
+	case "smtp":
+		return NewSMTPMailer(SMTPOptions{
+			Host:       cfg.SMTPHost,
+			Port:       cfg.SMTPPort,
+			Username:   cfg.SMTPUsername,
+			Password:   cfg.SMTPPassword,
+			Encryption: cfg.SMTPEncryption,
+			Timeout:    cfg.Timeout,
+		}, from), nil
+	case "file":
+		return NewFileMailer(cfg.FileDir, from)
+	case "log":
+		return NewLogMailer(log, from), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail transport: %s", cfg.Transport)
+	}
+}
+
+// envelope resolves the sender and validates the recipients of msg
+func envelope(msg *Message, defaultFrom string) (*mail.Address, []*mail.Address, error) {
+	fromHeader := msg.From
+	if fromHeader == "" {
+		fromHeader = defaultFrom
+	}
+
+	from, err := mail.ParseAddress(fromHeader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sender %q: %w", fromHeader, err)
+	}
+
+	if len(msg.To) == 0 {
+		return nil, nil, fmt.Errorf("message has no recipients")
+	}
+
+	to := make([]*mail.Address, 0, len(msg.To))
+	for _, addr := range msg.To {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid recipient %q: %w", addr, err)
+		}
+		to = append(to, parsed)
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPOptions configures the SMTP transport. Encryption is "none", "starttls"
+// or "tls" (implicit TLS, usually port 465).
+type SMTPOptions struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	Encryption string
+	Timeout    time.Duration
+}
+
+// SMTPMailer delivers messages to an SMTP relay, one connection per message
+type SMTPMailer struct {
+	opts SMTPOptions
+	from string
+}
+
+// NewSMTPMailer creates an SMTPMailer that sends as from unless a message sets its own sender
+func NewSMTPMailer(opts SMTPOptions, from string) *SMTPMailer {
+	return &SMTPMailer{opts: opts, from: from}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	from, to, err := envelope(msg, m.from)
+	if err != nil {
+		return err
+	}
+
+	raw, err := buildMIME(msg, from, to)
+	if err != nil {
+		return err
+	}
+
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+
+	// net/smtp has no context support, so the deadline bounds the whole exchange
+	deadline := time.Now().Add(m.opts.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.opts.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if m.opts.Encryption == "starttls" {
+		if err := client.StartTLS(&tls.Config{ServerName: m.opts.Host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+
+	if m.opts.Username != "" {
+		auth := smtp.PlainAuth("", m.opts.Username, m.opts.Password, m.opts.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr.Address); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", addr.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return fmt.Errorf("failed to write SMTP message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func (m *SMTPMailer) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(m.opts.Host, strconv.Itoa(m.opts.Port))
+	dialer := &net.Dialer{Timeout: m.opts.Timeout}
+
+	if m.opts.Encryption == "tls" {
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config:    &tls.Config{ServerName: m.opts.Host},
+		}
+		return tlsDialer.DialContext(ctx, "tcp", addr)
+	}
+
+	return dialer.DialContext(ctx, "tcp", addr)
+}
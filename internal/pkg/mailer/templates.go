@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Renderer turns named email templates into messages. Each template is a
+// <name>.txt file defining "subject" and "content", plus an optional
+// <name>.html defining "content"; both are wrapped in the matching layout
+// from templates/layouts.
+type Renderer struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// NewRenderer parses the embedded templates
+func NewRenderer() (*Renderer, error) {
+	return NewRendererFS(templateFS)
+}
+
+// NewRendererFS parses templates from fsys, which must contain a templates
+// directory laid out like the embedded one
+func NewRendererFS(fsys fs.FS) (*Renderer, error) {
+	r := &Renderer{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+
+	pages, err := fs.Glob(fsys, "templates/*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, page := range pages {
+		name := strings.TrimSuffix(path.Base(page), ".txt")
+
+		textTmpl, err := texttemplate.ParseFS(fsys, "templates/layouts/base.txt", page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name, err)
+		}
+		subject := textTmpl.Lookup("subject")
+		if subject == nil {
+			return nil, fmt.Errorf("email template %s does not define a subject", name)
+		}
+		r.text[name] = textTmpl
+
+		htmlPage := "templates/" + name + ".html"
+		if _, err := fs.Stat(fsys, htmlPage); err != nil {
+			continue
+		}
+
+		// The HTML title reuses the subject from the text template. The tree is
+		// copied because html/template rewrites it while escaping.
+		htmlTmpl, err := htmltemplate.New(name).ParseFS(fsys, "templates/layouts/base.html", htmlPage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name, err)
+		}
+		if _, err := htmlTmpl.AddParseTree("subject", subject.Tree.Copy()); err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name, err)
+		}
+		r.html[name] = htmlTmpl
+	}
+
+	return r, nil
+}
+
+// Render executes the named template with data and returns a message without recipients
+func (r *Renderer) Render(name string, data interface{}) (*Message, error) {
+	textTmpl, ok := r.text[name]
+	if !ok {
+		return nil, fmt.Errorf("email template not found: %s", name)
+	}
+
+	var subject, text bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := textTmpl.ExecuteTemplate(&text, "layout", data); err != nil {
+		return nil, fmt.Errorf("failed to render text body of %s: %w", name, err)
+	}
+
+	msg := &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}
+
+	if htmlTmpl, ok := r.html[name]; ok {
+		var html bytes.Buffer
+		if err := htmlTmpl.ExecuteTemplate(&html, "layout", data); err != nil {
+			return nil, fmt.Errorf("failed to render HTML body of %s: %w", name, err)
+		}
+		msg.HTML = html.String()
+	}
+
+	return msg, nil
+}
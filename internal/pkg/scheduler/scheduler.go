@@ -0,0 +1,209 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/yantology/golang_template/internal/pkg/logger"
+)
+
+var ErrDuplicateJob = errors.New("job already registered")
+
+// Job is a unit of background work
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Jitter adds a random delay in [0, Jitter) before every run so replicas
+	// do not all wake up at the same instant
+	Jitter time.Duration
+	// Timeout bounds a single run; zero means no limit besides shutdown
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Locker makes sure a job runs on only one replica at a time
+type Locker interface {
+	// TryLock acquires the lock for name without waiting. The returned
+	// function releases it and is only valid when acquired is true.
+	TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error)
+}
+
+// Scheduler runs registered jobs in the background until stopped
+type Scheduler struct {
+	locker Locker
+	logger logger.Logger
+
+	mu      sync.Mutex
+	jobs    map[string]*scheduledJob
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+type scheduledJob struct {
+	Job
+	// inFlight keeps runs of the same job from overlapping
+	inFlight sync.Mutex
+}
+
+// New creates a scheduler. A nil locker runs jobs on every replica.
+func New(locker Locker, log logger.Logger) *Scheduler {
+	if locker == nil {
+		locker = localLocker{}
+	}
+
+	return &Scheduler{
+		locker: locker,
+		logger: log,
+		jobs:   make(map[string]*scheduledJob),
+	}
+}
+
+// Register adds a job. Jobs registered after Start are not run.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return fmt.Errorf("job requires a name, schedule and run function")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, job.Name)
+	}
+
+	s.jobs[job.Name] = &scheduledJob{Job: job}
+	return nil
+}
+
+// Start launches one goroutine per registered job
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.running.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stop cancels running jobs and waits for them to return or ctx to expire
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunNow runs the named job immediately, honoring single-flight and the lock
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown job: %s", name)
+	}
+
+	return s.run(ctx, job)
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *scheduledJob) {
+	defer s.running.Done()
+
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			s.logger.WithField("job", job.Name).Warn("Job schedule never fires again")
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next) + jitter(job.Jitter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.run(ctx, job); err != nil {
+			s.logger.WithField("job", job.Name).WithError(err).Error("Job failed")
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job *scheduledJob) error {
+	log := s.logger.WithField("job", job.Name)
+
+	if !job.inFlight.TryLock() {
+		log.Debug("Previous run still in progress, skipping")
+		return nil
+	}
+	defer job.inFlight.Unlock()
+
+	unlock, acquired, err := s.locker.TryLock(ctx, job.Name)
+	if err != nil {
+		return fmt.Errorf("failed to acquire job lock: %w", err)
+	}
+	if !acquired {
+		log.Debug("Job is running on another replica, skipping")
+		return nil
+	}
+	defer unlock()
+
+	runCtx := ctx
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	if err := job.Run(runCtx); err != nil {
+		return err
+	}
+
+	log.WithField("duration", time.Since(start).String()).Debug("Job completed")
+	return nil
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// localLocker always grants the lock, for single-replica deployments
+type localLocker struct{}
+
+func (localLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	return func() {}, true, nil
+}
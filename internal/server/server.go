@@ -13,23 +13,47 @@ import (
 	"github.com/yantology/golang_template/internal/api/handlers"
 	"github.com/yantology/golang_template/internal/api/routes"
 	"github.com/yantology/golang_template/internal/config"
+	"github.com/yantology/golang_template/internal/pkg/auth"
+	"github.com/yantology/golang_template/internal/pkg/authz"
+	"github.com/yantology/golang_template/internal/pkg/logger"
 	"github.com/yantology/golang_template/pkg/response"
 )
 
+// Dependencies holds the services the server exposes over HTTP
+type Dependencies struct {
+	DB          *sql.DB
+	Logger      logger.Logger
+	AuthService *auth.Service
+	JWTKeys     *auth.KeySet
+	// Authorizer makes per-resource decisions for handlers
+	Authorizer *authz.Authorizer
+}
+
 // Server represents the HTTP server
 type Server struct {
 	config *config.Config
-	db     *sql.DB
+	deps   Dependencies
 	router *gin.Engine
 	server *http.Server
 }
 
 // New creates a new server instance
-func New(cfg *config.Config, db *sql.DB) *Server {
+func New(cfg *config.Config, deps Dependencies) *Server {
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
 
+	// Client IPs key the login lockout, so forwarding headers are only
+	// believed from configured proxies. Without any, the TCP peer is the client.
+	trustedProxies := cfg.Server.TrustedProxies
+	if len(trustedProxies) == 0 {
+		trustedProxies = nil
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		deps.Logger.WithError(err).Error("Invalid trusted proxies, trusting none")
+		_ = router.SetTrustedProxies(nil)
+	}
+
 	// Add global middleware
 	router.Use(gin.Recovery())
 	router.Use(gin.Logger())
@@ -38,19 +62,26 @@ func New(cfg *config.Config, db *sql.DB) *Server {
 	corsConfig := cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", auth.APIKeyHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
 	router.Use(cors.New(corsConfig))
 
 	// Health check endpoint
-	router.GET("/health", healthCheckHandler(db))
+	router.GET("/health", healthCheckHandler(deps.DB))
 
 	server := &Server{
 		config: cfg,
-		db:     db,
+		deps:   deps,
 		router: router,
+		server: &http.Server{
+			Addr:         cfg.Server.GetAddress(),
+			Handler:      router,
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+		},
 	}
 
 	// Setup API routes
@@ -61,14 +92,6 @@ func New(cfg *config.Config, db *sql.DB) *Server {
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
-	addr := fmt.Sprintf("%s:%s", s.config.Server.Host, s.config.Server.Port)
-	s.server = &http.Server{
-		Addr:    addr,
-		Handler: s.router,
-	}
-
-	fmt.Printf("Server starting on %s\n", addr)
-
 	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
@@ -78,27 +101,33 @@ func (s *Server) Start() error {
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
-	fmt.Println("Server shutting down...")
-	
-	if s.server != nil {
-		return s.server.Shutdown(ctx)
-	}
-	
-	return nil
+	return s.server.Shutdown(ctx)
 }
 
 // setupRoutes configures all API routes
 func (s *Server) setupRoutes() {
 	// Initialize handlers
-	handler := handlers.NewHandler()
+	handler := handlers.NewHandler(s.deps.Authorizer)
 
 	// API version 1
 	v1 := s.router.Group("/api/v1")
-	
+
 	// Setup routes
 	routes.SetupRoutes(v1, handler)
-}
 
+	// Key discovery lives at the well-known path outside the versioned API
+	if s.deps.JWTKeys != nil {
+		s.router.GET("/.well-known/jwks.json", handlers.NewJWKSHandler(s.deps.JWTKeys).JWKS)
+	}
+
+	if s.deps.AuthService != nil {
+		authHandler := handlers.NewAuthHandler(s.deps.AuthService)
+		authMiddleware := auth.NewMiddleware(s.deps.AuthService)
+		routes.SetupAuthRoutes(v1, authHandler, authMiddleware)
+		routes.SetupOAuthRoutes(v1, authHandler, authMiddleware)
+		routes.SetupAdminRoutes(v1, authHandler, authMiddleware)
+	}
+}
 
 // healthCheckHandler performs a basic health check
 func healthCheckHandler(db *sql.DB) gin.HandlerFunc {
@@ -115,4 +144,4 @@ func healthCheckHandler(db *sql.DB) gin.HandlerFunc {
 			"timestamp": time.Now().UTC(),
 		})
 	}
-}
\ No newline at end of file
+}